@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxEnvelopeBytes 單一請求本文的上限
+const maxEnvelopeBytes = 1 << 20
+
+// defaultJobRetention 完成的工作預設保留多久供查詢
+const defaultJobRetention = 10 * time.Minute
+
+// JobStatus 工作的狀態
+type JobStatus string
+
+const (
+	JobQueued JobStatus = "queued"
+	JobDone   JobStatus = "done"
+	// JobFailed 物品處理時回傳錯誤
+	JobFailed JobStatus = "failed"
+)
+
+// Job 透過 HTTP 提交的一件物品及其處理結果
+type Job struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	ItemID    int             `json:"item_id"`
+	Key       string          `json:"key,omitempty"`
+	Status    JobStatus       `json:"status"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Employee  int             `json:"employee,omitempty"`
+	Submitted time.Time       `json:"submitted"`
+	Finished  *time.Time      `json:"finished,omitempty"`
+}
+
+// JobServer 以 HTTP 接收物品信封送入程序內的流水線, 並保存每件工作的狀態供查詢:
+//
+//	POST /jobs       提交一個信封, 回傳 202 及工作編號; 佇列已滿時回傳 429,
+//	                 冪等鍵重複時回傳 200 及原本的工作
+//	GET  /jobs/{id}  查詢工作的狀態及結果
+//	GET  /employees  所有員工目前的狀態
+//
+// 完成的工作保留 Retention 後移除, 之後查詢回傳 404
+type JobServer struct {
+	// Retention 完成的工作保留多久, 預設 10 分鐘, 小於等於 0 時一直保留; 需在 Start 前設定
+	Retention time.Duration
+
+	line    *AssemblyLine
+	codec   JSONCodec
+	results chan Result
+	// drained results 全部讀完後關閉
+	drained chan struct{}
+	mux     *http.ServeMux
+	// now 為時鐘, 測試時可替換
+	now func() time.Time
+
+	mu sync.Mutex
+	// started 是否已呼叫 Start, 未啟動時 Stop 不等待結果
+	started bool
+	jobs    map[string]*Job
+	// pending 提交順序 (結果的 Seq) 對應尚未完成的工作,
+	// early 為工作登記前就已送達的結果
+	pending map[int]*Job
+	early   map[int]Result
+	// expiry 依完成先後排列的工作及其移除時間
+	expiry []jobExpiry
+	// keys 冪等鍵對應的工作編號, 重複提交時回傳原本的工作
+	keys   map[string]string
+	nextID int
+}
+
+// jobExpiry 完成的工作何時移除
+type jobExpiry struct {
+	id string
+	at time.Time
+}
+
+// NewJobServer 創建有 numEmployees 位員工的流水線及其 HTTP 介面, opts 同 NewAssemblyLine
+func NewJobServer(registry *ItemRegistry, numEmployees int, opts ...Option) *JobServer {
+	s := &JobServer{
+		Retention: defaultJobRetention,
+		codec:     JSONCodec{Registry: registry},
+		results:   make(chan Result, 64),
+		drained:   make(chan struct{}),
+		mux:       http.NewServeMux(),
+		now:       time.Now,
+		jobs:      make(map[string]*Job),
+		pending:   make(map[int]*Job),
+		early:     make(map[int]Result),
+		keys:      make(map[string]string),
+	}
+	opts = append(opts, WithResults(CompletionOrder), WithResultSink(s.results))
+	s.line = NewAssemblyLine(numEmployees, opts...)
+	s.mux.HandleFunc("POST /jobs", s.submit)
+	s.mux.HandleFunc("GET /jobs/{id}", s.status)
+	s.mux.HandleFunc("GET /employees", s.employees)
+	return s
+}
+
+// Start 啟動流水線
+func (s *JobServer) Start() {
+	s.mu.Lock()
+	s.started = true
+	s.mu.Unlock()
+	s.line.Start()
+	go s.collect()
+}
+
+// Stop 停止接受工作, 等待已提交的工作完成後回傳統計; 可重複呼叫
+func (s *JobServer) Stop() Stats {
+	stats := s.line.Stop()
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+	if started {
+		<-s.drained
+	}
+	return stats
+}
+
+// Job 回傳工作目前狀態的副本
+func (s *JobServer) Job(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+func (s *JobServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// collect 依完成的結果更新工作狀態
+func (s *JobServer) collect() {
+	defer close(s.drained)
+	for r := range s.results {
+		s.mu.Lock()
+		if job, ok := s.pending[r.Seq]; ok {
+			delete(s.pending, r.Seq)
+			s.finish(job, r)
+		} else {
+			s.early[r.Seq] = r
+		}
+		s.evict()
+		s.mu.Unlock()
+	}
+}
+
+// finish 以結果更新工作, 並排定移除時間; 呼叫時需持有鎖
+func (s *JobServer) finish(job *Job, r Result) {
+	if r.Value != nil {
+		data, err := json.Marshal(r.Value)
+		if err != nil {
+			data, _ = json.Marshal(fmt.Sprint(r.Value))
+		}
+		job.Result = data
+	}
+	finished := r.Finished
+	job.Status = JobDone
+	job.Employee = r.Employee
+	job.Finished = &finished
+	if r.Err != nil {
+		job.Status = JobFailed
+		job.Error = r.Err.Error()
+	}
+	if s.Retention > 0 {
+		s.expiry = append(s.expiry, jobExpiry{id: job.ID, at: s.now().Add(s.Retention)})
+	}
+}
+
+// evict 移除超過保留時間的工作及其冪等鍵; 呼叫時需持有鎖
+func (s *JobServer) evict() {
+	now := s.now()
+	n := 0
+	for ; n < len(s.expiry) && !s.expiry[n].at.After(now); n++ {
+		id := s.expiry[n].id
+		if job, ok := s.jobs[id]; ok && s.keys[job.Key] == id {
+			delete(s.keys, job.Key)
+		}
+		delete(s.jobs, id)
+	}
+	s.expiry = s.expiry[n:]
+}
+
+// submit 處理 POST /jobs
+func (s *JobServer) submit(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxEnvelopeBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	env, err := s.codec.DecodeEnvelope(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	item, err := s.codec.Registry.Unwrap(env)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	key := env.Key
+	if key == "" {
+		key = idempotencyKey(item)
+	}
+
+	job, err := s.enqueue(item, env, key, false)
+	switch {
+	case err == nil:
+		w.Header().Set("Location", "/jobs/"+job.ID)
+		writeJSON(w, http.StatusAccepted, job)
+	case errors.Is(err, ErrDuplicate) && job.ID != "":
+		w.Header().Set("Location", "/jobs/"+job.ID)
+		writeJSON(w, http.StatusOK, job)
+	case errors.Is(err, ErrDuplicate):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, ErrQueueFull):
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusTooManyRequests, err)
+	case errors.Is(err, ErrStopped):
+		writeError(w, http.StatusServiceUnavailable, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// Resume 將從預寫日誌恢復的物品登記為工作後提交, 佇列已滿時等待; 需在 Start 之後呼叫
+func (s *JobServer) Resume(items []Item) error {
+	for _, item := range items {
+		env, err := s.codec.Registry.Wrap(item)
+		if err != nil {
+			return err
+		}
+		if _, err := s.enqueue(item, env, env.Key, true); err != nil && !errors.Is(err, ErrDuplicate) {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue 登記工作後提交物品, wait 為 false 時佇列已滿不等待;
+// 冪等鍵重複且原本的工作仍在時, 回傳原本的工作及 ErrDuplicate
+func (s *JobServer) enqueue(item Item, env Envelope, key string, wait bool) (Job, error) {
+	// 先登記工作再提交, 以便重複的冪等鍵可以找到原本的工作
+	s.mu.Lock()
+	s.evict()
+	s.nextID++
+	job := &Job{
+		ID:        strconv.Itoa(s.nextID),
+		Type:      env.Type,
+		ItemID:    env.ID,
+		Key:       key,
+		Status:    JobQueued,
+		Submitted: time.Now(),
+	}
+	s.jobs[job.ID] = job
+	prev, hadPrev := s.keys[key]
+	if key != "" {
+		s.keys[key] = job.ID
+	}
+	s.mu.Unlock()
+
+	seq, err := s.line.submitKey(item, key, wait)
+	if err != nil {
+		s.mu.Lock()
+		delete(s.jobs, job.ID)
+		if key != "" && s.keys[key] == job.ID {
+			if hadPrev {
+				s.keys[key] = prev
+			} else {
+				delete(s.keys, key)
+			}
+		}
+		var resp Job
+		if original, ok := s.jobs[s.keys[key]]; ok && errors.Is(err, ErrDuplicate) {
+			resp = *original
+		}
+		s.mu.Unlock()
+		return resp, err
+	}
+
+	// 物品可能在登記提交順序前就已完成
+	s.mu.Lock()
+	if r, ok := s.early[seq]; ok {
+		delete(s.early, seq)
+		s.finish(job, r)
+	} else {
+		s.pending[seq] = job
+	}
+	resp := *job
+	s.mu.Unlock()
+	return resp, nil
+}
+
+// status 處理 GET /jobs/{id}
+func (s *JobServer) status(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.Job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %q not found", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// employees 處理 GET /employees
+func (s *JobServer) employees(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.line.EmployeeStates())
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// runJobServer 在 addr 上提供 HTTP 介面, 先提交從預寫日誌恢復的物品,
+// 收到中斷訊號時停止並打印統計
+func runJobServer(addr string, opts []Option, recovered []Item) error {
+	s := NewJobServer(BuiltinItems(), numEmployees, opts...)
+	s.Start()
+	if err := s.Resume(recovered); err != nil {
+		s.Stop()
+		return err
+	}
+	srv := &http.Server{Addr: addr, Handler: s}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	fmt.Printf("在 %s 接收工作, 按 Ctrl+C 停止\n", addr)
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = srv.Shutdown(shutdown)
+		cancel()
+	}
+	s.Stop().Print(os.Stdout)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// squareItem 處理後輸出編號平方的測試物品
+type squareItem struct {
+	id int
+	d  time.Duration
+}
+
+func (i *squareItem) Process() { i.ProcessResult() }
+
+func (i *squareItem) ProcessResult() (any, error) {
+	time.Sleep(i.d)
+	return i.id * i.id, nil
+}
+
+func (i *squareItem) String() string { return "square #" + strconv.Itoa(i.id) }
+
+func (i *squareItem) Type() string { return "square" }
+
+// newTestJobServer 註冊 square 類型, 處理時間由信封內容決定
+func newTestJobServer(t *testing.T, employees int, opts ...Option) (*JobServer, *httptest.Server) {
+	t.Helper()
+	registry := BuiltinItems()
+	registry.Register(ItemType{
+		Name: "square",
+		Encode: func(item Item) (int, json.RawMessage, error) {
+			i := item.(*squareItem)
+			payload, err := json.Marshal(i.d)
+			return i.id, payload, err
+		},
+		Decode: func(id int, payload json.RawMessage) (Item, error) {
+			var d time.Duration
+			if len(payload) > 0 {
+				if err := json.Unmarshal(payload, &d); err != nil {
+					return nil, err
+				}
+			}
+			return &squareItem{id: id, d: d}, nil
+		},
+	})
+	s := NewJobServer(registry, employees, opts...)
+	s.Start()
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// postJob 提交一個信封, 回傳狀態碼及回應內容
+func postJob(t *testing.T, ts *httptest.Server, body string) (*http.Response, Job) {
+	t.Helper()
+	resp, err := http.Post(ts.URL+"/jobs", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /jobs error = %v", err)
+	}
+	defer resp.Body.Close()
+	var job Job
+	json.NewDecoder(resp.Body).Decode(&job)
+	return resp, job
+}
+
+// TestJobServer_SubmitAndPoll 驗證提交後可依工作編號查詢到結果
+func TestJobServer_SubmitAndPoll(t *testing.T) {
+	s, ts := newTestJobServer(t, 2)
+
+	resp, job := postJob(t, ts, `{"type":"square","id":7,"payload":20000000}`)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST status = %d, want 202", resp.StatusCode)
+	}
+	if job.ID == "" || job.Status != JobQueued || job.Type != "square" || job.ItemID != 7 {
+		t.Errorf("POST job = %+v, want queued square #7", job)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/jobs/"+job.ID {
+		t.Errorf("Location = %q, want /jobs/%s", loc, job.ID)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	var got Job
+	for time.Now().Before(deadline) {
+		r, err := http.Get(ts.URL + "/jobs/" + job.ID)
+		if err != nil {
+			t.Fatalf("GET error = %v", err)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		r.Body.Close()
+		if got.Status != JobQueued {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got.Status != JobDone || string(got.Result) != "49" || got.Employee == 0 || got.Finished == nil {
+		t.Errorf("GET job = %+v, want done with result 49", got)
+	}
+
+	stats := s.Stop()
+	if stats.TotalProcessed() != 1 {
+		t.Errorf("TotalProcessed() = %d, want 1", stats.TotalProcessed())
+	}
+	if resp, _ := postJob(t, ts, `{"type":"square","id":1}`); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("POST after Stop status = %d, want 503", resp.StatusCode)
+	}
+}
+
+// TestJobServer_Validation 驗證無效的請求
+func TestJobServer_Validation(t *testing.T) {
+	s, ts := newTestJobServer(t, 1)
+	defer s.Stop()
+
+	for name, body := range map[string]string{
+		"malformed":     `{"type":`,
+		"unknown type":  `{"type":"nope","id":1}`,
+		"unknown field": `{"type":"square","id":1,"color":"red"}`,
+		"bad payload":   `{"type":"square","id":1,"payload":"soon"}`,
+	} {
+		if resp, _ := postJob(t, ts, body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, resp.StatusCode)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/jobs/42")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET unknown job status = %d, want 404", resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/jobs")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /jobs status = %d, want 405", resp.StatusCode)
+	}
+}
+
+// TestJobServer_Backpressure 驗證佇列已滿時回傳 429, 且被拒絕的工作無法查詢
+func TestJobServer_Backpressure(t *testing.T) {
+	s, ts := newTestJobServer(t, 1, WithQueueCapacity(1))
+
+	accepted, rejected := 0, 0
+	for i := 1; i <= 4; i++ {
+		resp, _ := postJob(t, ts, `{"type":"square","id":`+strconv.Itoa(i)+`,"payload":200000000}`)
+		switch resp.StatusCode {
+		case http.StatusAccepted:
+			accepted++
+		case http.StatusTooManyRequests:
+			rejected++
+			if resp.Header.Get("Retry-After") == "" {
+				t.Error("429 without Retry-After")
+			}
+		default:
+			t.Errorf("POST status = %d, want 202 or 429", resp.StatusCode)
+		}
+	}
+	// 一件處理中加上一件在佇列中
+	if accepted > 2 || rejected == 0 {
+		t.Errorf("accepted = %d, rejected = %d, want at most 2 accepted", accepted, rejected)
+	}
+	if _, ok := s.Job(strconv.Itoa(accepted + 1)); ok {
+		t.Error("rejected job is still registered")
+	}
+	if got := s.Stop().TotalProcessed(); got != accepted {
+		t.Errorf("TotalProcessed() = %d, want %d", got, accepted)
+	}
+}
+
+// TestJobServer_IdempotencyKey 驗證重複的冪等鍵回傳原本的工作而不再處理
+func TestJobServer_IdempotencyKey(t *testing.T) {
+	s, ts := newTestJobServer(t, 1, WithDedup(time.Minute))
+
+	first, job := postJob(t, ts, `{"type":"square","id":3,"key":"retry-1"}`)
+	if first.StatusCode != http.StatusAccepted || job.Key != "retry-1" {
+		t.Fatalf("first POST = %d %+v, want 202 with key", first.StatusCode, job)
+	}
+	again, dup := postJob(t, ts, `{"type":"square","id":3,"key":"retry-1"}`)
+	if again.StatusCode != http.StatusOK || dup.ID != job.ID {
+		t.Errorf("duplicate POST = %d job %s, want 200 job %s", again.StatusCode, dup.ID, job.ID)
+	}
+	if other, _ := postJob(t, ts, `{"type":"square","id":3,"key":"retry-2"}`); other.StatusCode != http.StatusAccepted {
+		t.Errorf("POST with new key status = %d, want 202", other.StatusCode)
+	}
+
+	stats := s.Stop()
+	if stats.TotalProcessed() != 2 || stats.Duplicates != 1 {
+		t.Errorf("processed = %d, Duplicates = %d, want 2 and 1", stats.TotalProcessed(), stats.Duplicates)
+	}
+}
+
+// TestJobServer_Employees 驗證員工狀態查詢
+func TestJobServer_Employees(t *testing.T) {
+	s, ts := newTestJobServer(t, 2)
+	postJob(t, ts, `{"type":"square","id":2}`)
+	s.Stop()
+
+	resp, err := http.Get(ts.URL + "/employees")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+	var states []EmployeeState
+	if err := json.NewDecoder(resp.Body).Decode(&states); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	if len(states) != 2 || states[0].ID != 1 || states[1].ID != 2 {
+		t.Fatalf("GET /employees = %+v, want employees 1 and 2", states)
+	}
+	if n := states[0].ProcessedByType["square"] + states[1].ProcessedByType["square"]; n != 1 {
+		t.Errorf("square processed = %d, want 1", n)
+	}
+}
+
+// TestJobServer_Retention 驗證完成的工作超過保留時間後移除
+func TestJobServer_Retention(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	s := NewJobServer(BuiltinItems(), 1)
+	s.Retention = time.Minute
+	s.now = clock.Now
+	s.Start()
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+
+	_, job := postJob(t, ts, `{"type":"Item1","id":1}`)
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, _ := s.Job(job.ID); got.Status != JobQueued {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got, ok := s.Job(job.ID); !ok || got.Status != JobDone {
+		t.Fatalf("Job() = %+v, %v, want done", got, ok)
+	}
+
+	// 下一次提交時移除到期的工作
+	clock.Advance(2 * time.Minute)
+	postJob(t, ts, `{"type":"Item1","id":2}`)
+	if _, ok := s.Job(job.ID); ok {
+		t.Error("Job() ok = true after retention, want evicted")
+	}
+	resp, err := http.Get(ts.URL + "/jobs/" + job.ID)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET evicted job status = %d, want 404", resp.StatusCode)
+	}
+	s.Stop()
+}
+
+// TestJobServer_StopIdempotent 驗證未啟動或已停止時 Stop 不會卡住
+func TestJobServer_StopIdempotent(t *testing.T) {
+	NewJobServer(BuiltinItems(), 1).Stop()
+
+	s, _ := newTestJobServer(t, 1)
+	s.Stop()
+	s.Stop()
+}
+
+// TestJobServer_Resume 驗證從預寫日誌恢復的物品登記為工作並處理完成
+func TestJobServer_Resume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "line.wal")
+	codec := JSONCodec{Registry: BuiltinItems()}
+	w, _, err := OpenWAL(path, codec)
+	if err != nil {
+		t.Fatalf("OpenWAL() error = %v", err)
+	}
+	w.Submit(&Item2{ID: 4})
+	w.Close()
+
+	w, pending, err := OpenWAL(path, codec)
+	if err != nil {
+		t.Fatalf("OpenWAL() error = %v", err)
+	}
+	defer w.Close()
+	s := NewJobServer(codec.Registry, 1, WithWAL(w))
+	s.Start()
+	if err := s.Resume(pending); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	stats := s.Stop()
+
+	job, ok := s.Job("1")
+	if !ok || job.Type != "Item2" || job.ItemID != 4 || job.Status != JobDone {
+		t.Errorf("Job(1) = %+v, %v, want done Item2 #4", job, ok)
+	}
+	if stats.TotalProcessed() != 1 || w.Pending() != 0 {
+		t.Errorf("TotalProcessed() = %d, Pending() = %d, want 1 and 0", stats.TotalProcessed(), w.Pending())
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import "time"
+
+// TypePair 物品類型的切換 (From -> To)
+type TypePair struct {
+	From string
+	To   string
+}
+
+// Changeover 員工從一種物品切換到另一種時需要的準備時間
+type Changeover map[TypePair]time.Duration
+
+// Cost 回傳從 from 切換到 to 的準備時間, 同類或尚未處理過物品時為 0
+func (c Changeover) Cost(from, to string) time.Duration {
+	if from == "" || from == to {
+		return 0
+	}
+	return c[TypePair{From: from, To: to}]
+}
+
+// UniformChangeover 任意兩種不同類型之間切換都需要 d
+func UniformChangeover(types []string, d time.Duration) Changeover {
+	c := make(Changeover)
+	for _, from := range types {
+		for _, to := range types {
+			if from != to {
+				c[TypePair{From: from, To: to}] = d
+			}
+		}
+	}
+	return c
+}
+
+// estimateFIFOChangeover 依提交順序模擬先進先出派發, 估算會產生的切換時間,
+// 每件物品的處理時間以 avg 中同類型的平均值計算
+func estimateFIFOChangeover(items []Item, employees []*Employee, avg map[string]time.Duration) time.Duration {
+	if len(employees) == 0 {
+		return 0
+	}
+	free := make([]time.Duration, len(employees))
+	last := make([]string, len(employees))
+
+	var total time.Duration
+	for _, item := range items {
+		// 最早空閒的員工取走下一件
+		i := 0
+		for j := range free {
+			if free[j] < free[i] {
+				i = j
+			}
+		}
+		kind := itemType(item)
+		cost := employees[i].Changeover.Cost(last[i], kind)
+		total += cost
+		free[i] += cost + avg[kind]
+		last[i] = kind
+	}
+	return total
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestChangeoverCost 驗證切換成本查詢
+func TestChangeoverCost(t *testing.T) {
+	c := Changeover{
+		{From: "A", To: "B"}: 10 * time.Millisecond,
+	}
+	tests := []struct {
+		from, to string
+		want     time.Duration
+	}{
+		{"", "A", 0},
+		{"A", "A", 0},
+		{"A", "B", 10 * time.Millisecond},
+		{"B", "A", 0},
+	}
+	for _, tt := range tests {
+		if got := c.Cost(tt.from, tt.to); got != tt.want {
+			t.Errorf("Cost(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	var empty Changeover
+	if got := empty.Cost("A", "B"); got != 0 {
+		t.Errorf("nil Changeover Cost = %v, want 0", got)
+	}
+}
+
+// TestUniformChangeover 驗證所有不同類型之間都有相同成本
+func TestUniformChangeover(t *testing.T) {
+	c := UniformChangeover([]string{"A", "B", "C"}, 5*time.Millisecond)
+	if len(c) != 6 {
+		t.Errorf("len = %d, want 6", len(c))
+	}
+	if got := c.Cost("C", "A"); got != 5*time.Millisecond {
+		t.Errorf("Cost(C, A) = %v, want 5ms", got)
+	}
+}
+
+// TestEstimateFIFOChangeover 驗證先進先出切換時間估算
+func TestEstimateFIFOChangeover(t *testing.T) {
+	employees := []*Employee{{ID: 1, Changeover: UniformChangeover([]string{"A", "B"}, time.Millisecond)}}
+	items := []Item{
+		&testItem{kind: "A"},
+		&testItem{kind: "B"},
+		&testItem{kind: "A"},
+		&testItem{kind: "A"},
+	}
+	avg := map[string]time.Duration{"A": time.Millisecond, "B": time.Millisecond}
+
+	if got := estimateFIFOChangeover(items, employees, avg); got != 2*time.Millisecond {
+		t.Errorf("estimateFIFOChangeover = %v, want 2ms", got)
+	}
+}
+
+// TestAssemblyLine_AffinityReducesChangeover 驗證類型親和派發減少切換次數
+func TestAssemblyLine_AffinityReducesChangeover(t *testing.T) {
+	items := make([]Item, 0, 12)
+	for i := 0; i < 6; i++ {
+		items = append(items, &testItem{kind: "A", id: i + 1}, &testItem{kind: "B", id: i + 1})
+	}
+	c := UniformChangeover([]string{"A", "B"}, time.Millisecond)
+
+	fifo := NewAssemblyLine(1, WithChangeover(c)).Run(items)
+	if fifo.Changeovers != 11 {
+		t.Errorf("FIFO Changeovers = %d, want 11", fifo.Changeovers)
+	}
+
+	affinity := NewAssemblyLine(1, WithChangeover(c), WithDispatcher(NewAffinityDispatcher)).Run(items)
+	if affinity.Changeovers != 1 {
+		t.Errorf("affinity Changeovers = %d, want 1", affinity.Changeovers)
+	}
+	if affinity.ChangeoverTime != time.Millisecond {
+		t.Errorf("affinity ChangeoverTime = %v, want 1ms", affinity.ChangeoverTime)
+	}
+	if affinity.FIFOChangeoverTime != 11*time.Millisecond {
+		t.Errorf("FIFOChangeoverTime = %v, want 11ms", affinity.FIFOChangeoverTime)
+	}
+}
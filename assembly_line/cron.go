@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CronSchedule 解析後的 cron 表達式, 欄位依序為 分 時 日 月 星期
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// 日或星期其中一個為 * 時兩者都要符合, 都有指定時符合任一個即可
+	domAny, dowAny bool
+}
+
+// cronFields 各欄位的名稱及範圍, 星期的 7 與 0 同為星期日
+var cronFields = [5]struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// cronMacros 常用排程的簡寫
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron 解析五個欄位的 cron 表達式, 支援 *, 數字, a-b, 逗號分隔及 /n 間隔,
+// 以及 @hourly, @daily 等簡寫
+func ParseCron(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[expr]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron: expected %d fields, got %d in %q", len(cronFields), len(fields), expr)
+	}
+
+	var bits [5]uint64
+	for i, field := range fields {
+		b, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("cron: invalid %s field %q: %w", cronFields[i].name, field, err)
+		}
+		bits[i] = b
+	}
+	// 星期 7 視為星期日
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+	return &CronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField 將單一欄位轉為位元集合
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step %q", stepText)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("bad value %q", from)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("bad value %q", to)
+				}
+			} else if hasStep {
+				// 5/15 表示從 5 開始每 15 一次
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", rng, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// dayMatches 日期是否符合日及星期欄位
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<t.Weekday()) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next 回傳 after 之後 (不含) 第一個符合的時間, 五年內都不符合時回傳零值
+func (c *CronSchedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute()+1, 0, 0, loc)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<t.Month()) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<t.Minute()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// MissedRunPolicy 流水線忙碌或排程暫停而錯過執行時間時的處理方式
+type MissedRunPolicy int
+
+const (
+	// SkipMissed 只執行最近一次, 略過其餘錯過的執行
+	SkipMissed MissedRunPolicy = iota
+	// CatchUpMissed 補上每一次錯過的執行
+	CatchUpMissed
+)
+
+// CronJobStats 單一定期任務的統計
+type CronJobStats struct {
+	Name string
+	// Runs 送出的物品數
+	Runs int
+	// Skipped 依 SkipMissed 略過的執行次數
+	Skipped int
+	// CaughtUp 依 CatchUpMissed 補上的執行次數
+	CaughtUp int
+}
+
+// cronJob 註冊的定期任務
+type cronJob struct {
+	schedule *CronSchedule
+	policy   MissedRunPolicy
+	produce  func(at time.Time) Item
+	next     time.Time
+	stats    CronJobStats
+}
+
+// due 回傳在 now 之前 (含) 到期的執行時間並推進下一次時間, 依策略處理錯過的執行
+func (j *cronJob) due(now time.Time) []time.Time {
+	var times []time.Time
+	missed := 0
+	for !j.next.IsZero() && !j.next.After(now) {
+		if j.policy == SkipMissed && len(times) > 0 {
+			times = times[:0]
+			missed++
+		}
+		times = append(times, j.next)
+		j.next = j.schedule.Next(j.next)
+	}
+	if j.policy == SkipMissed {
+		j.stats.Skipped += missed
+	} else if len(times) > 1 {
+		j.stats.CaughtUp += len(times) - 1
+	}
+	j.stats.Runs += len(times)
+	return times
+}
+
+// Cron 依 cron 表達式定期產生物品, 送入以 Start 啟動的流水線
+type Cron struct {
+	line *AssemblyLine
+	// now 及 after 為時鐘, 測試時可替換
+	now   func() time.Time
+	after func(d time.Duration) <-chan time.Time
+
+	mu     sync.Mutex
+	jobs   []*cronJob
+	paused bool
+	// wake 新增任務或暫停狀態改變時喚醒 Run
+	wake chan struct{}
+}
+
+// NewCron 創建送入 line 的定期任務排程器
+func NewCron(line *AssemblyLine) *Cron {
+	return &Cron{
+		line:  line,
+		now:   time.Now,
+		after: time.After,
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Add 註冊定期任務, 每到 spec 排定的時間以 produce 產生一件物品
+func (c *Cron) Add(name, spec string, policy MissedRunPolicy, produce func(at time.Time) Item) error {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.jobs = append(c.jobs, &cronJob{
+		schedule: schedule,
+		policy:   policy,
+		produce:  produce,
+		next:     schedule.Next(c.now()),
+		stats:    CronJobStats{Name: name},
+	})
+	c.mu.Unlock()
+	c.signal()
+	return nil
+}
+
+// Pause 暫停送出物品, 期間到期的執行在 Resume 後依各任務的策略處理
+func (c *Cron) Pause() {
+	c.mu.Lock()
+	c.paused = true
+	c.mu.Unlock()
+	c.signal()
+}
+
+// Resume 恢復送出物品
+func (c *Cron) Resume() {
+	c.mu.Lock()
+	c.paused = false
+	c.mu.Unlock()
+	c.signal()
+}
+
+func (c *Cron) signal() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Stats 各任務的統計, 依註冊順序排列
+func (c *Cron) Stats() []CronJobStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := make([]CronJobStats, len(c.jobs))
+	for i, j := range c.jobs {
+		stats[i] = j.stats
+	}
+	return stats
+}
+
+// Run 依排程將物品送入流水線, 直到 ctx 結束時回傳 nil;
+// 流水線未啟動或已停止時回傳 ErrStopped.
+// Submit 因佇列已滿而等待時, 期間到期的執行視為錯過
+func (c *Cron) Run(ctx context.Context) error {
+	for ctx.Err() == nil {
+		c.mu.Lock()
+		paused := c.paused
+		var next time.Time
+		for _, j := range c.jobs {
+			if !j.next.IsZero() && (next.IsZero() || j.next.Before(next)) {
+				next = j.next
+			}
+		}
+		c.mu.Unlock()
+
+		var fire <-chan time.Time
+		if !paused && !next.IsZero() {
+			fire = c.after(next.Sub(c.now()))
+		}
+		select {
+		case <-fire:
+		case <-c.wake:
+			continue
+		case <-ctx.Done():
+			return nil
+		}
+
+		if err := c.fire(c.now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fire 送出所有到期任務的物品
+func (c *Cron) fire(now time.Time) error {
+	type run struct {
+		job *cronJob
+		at  time.Time
+	}
+	var runs []run
+	c.mu.Lock()
+	for _, j := range c.jobs {
+		for _, at := range j.due(now) {
+			runs = append(runs, run{job: j, at: at})
+		}
+	}
+	c.mu.Unlock()
+
+	for _, r := range runs {
+		if err := c.line.Submit(r.job.produce(r.at)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
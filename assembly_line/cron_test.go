@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestParseCron_Invalid 驗證錯誤的表達式
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@every",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) error = nil, want error", expr)
+		}
+	}
+}
+
+// TestCronSchedule_Next 驗證下一次執行時間
+func TestCronSchedule_Next(t *testing.T) {
+	// 2024-03-01 是星期五
+	base := time.Date(2024, 3, 1, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"*/15 * * * *", base, time.Date(2024, 3, 1, 10, 15, 0, 0, time.UTC)},
+		{"* * * * *", base, time.Date(2024, 3, 1, 10, 8, 0, 0, time.UTC)},
+		{"5/20 * * * *", base, time.Date(2024, 3, 1, 10, 25, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", base, time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)},
+		{"30 8,17 * * *", base, time.Date(2024, 3, 1, 17, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", base, time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", base, time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", base, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		// 日與星期都有指定時符合任一個: 13 號或星期五
+		{"0 0 13 * 5", base, time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", base, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", base, time.Time{}},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) error = %v", tt.expr, err)
+		}
+		if got := c.Next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%v) = %v, want %v", tt.expr, tt.after, got, tt.want)
+		}
+	}
+}
+
+// TestCronJob_MissedRuns 驗證錯過的執行依策略略過或補上
+func TestCronJob_MissedRuns(t *testing.T) {
+	schedule, _ := ParseCron("* * * * *")
+	first := time.Date(2024, 3, 1, 10, 1, 0, 0, time.UTC)
+	now := time.Date(2024, 3, 1, 10, 5, 30, 0, time.UTC)
+
+	skip := &cronJob{schedule: schedule, policy: SkipMissed, next: first}
+	if got := skip.due(now); len(got) != 1 || got[0].Minute() != 5 {
+		t.Errorf("skip due = %v, want only 10:05", got)
+	}
+	if skip.stats.Skipped != 4 || skip.stats.Runs != 1 {
+		t.Errorf("skip stats = %+v, want 4 skipped, 1 run", skip.stats)
+	}
+
+	catchUp := &cronJob{schedule: schedule, policy: CatchUpMissed, next: first}
+	if got := catchUp.due(now); len(got) != 5 {
+		t.Errorf("catch up due = %v, want 5 runs", got)
+	}
+	if catchUp.stats.CaughtUp != 4 || catchUp.stats.Runs != 5 {
+		t.Errorf("catch up stats = %+v, want 4 caught up, 5 runs", catchUp.stats)
+	}
+	if !catchUp.next.Equal(now.Truncate(time.Minute).Add(time.Minute)) {
+		t.Errorf("next = %v, want 10:06", catchUp.next)
+	}
+	if got := catchUp.due(now); len(got) != 0 {
+		t.Errorf("due again = %v, want none", got)
+	}
+}
+
+// fakeClock 等待時直接把時間往前推的測試時鐘
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	if d > 0 {
+		c.Advance(d)
+	}
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
+
+// newTestCron 創建使用測試時鐘的排程器
+func newTestCron(line *AssemblyLine, clock *fakeClock) *Cron {
+	c := NewCron(line)
+	c.now = clock.Now
+	c.after = clock.After
+	return c
+}
+
+// TestCron_Run 驗證定期任務依排程送出物品至流水線
+func TestCron_Run(t *testing.T) {
+	line := NewAssemblyLine(2)
+	line.Start()
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 10, 7, 0, 0, time.UTC)}
+	c := newTestCron(line, clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var at []time.Time
+	err := c.Add("report", "*/10 * * * *", SkipMissed, func(t time.Time) Item {
+		at = append(at, t)
+		if len(at) == 3 {
+			cancel()
+		}
+		return &testItem{kind: "report", id: len(at)}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	stats := line.Stop()
+	if stats.Submitted != 3 || stats.TotalProcessed() != 3 {
+		t.Errorf("Submitted = %d, processed = %d, want 3", stats.Submitted, stats.TotalProcessed())
+	}
+	for i, want := range []int{10, 20, 30} {
+		if at[i].Minute() != want {
+			t.Errorf("run %d at %v, want minute %d", i, at[i], want)
+		}
+	}
+	if got := c.Stats(); len(got) != 1 || got[0].Name != "report" || got[0].Runs != 3 {
+		t.Errorf("Stats() = %+v", got)
+	}
+}
+
+// TestCron_PauseResume 驗證暫停期間錯過的執行在恢復後依策略處理
+func TestCron_PauseResume(t *testing.T) {
+	for _, tt := range []struct {
+		policy MissedRunPolicy
+		runs   int
+	}{
+		{SkipMissed, 1},
+		{CatchUpMissed, 6},
+	} {
+		line := NewAssemblyLine(1)
+		line.Start()
+		clock := &fakeClock{now: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}
+		c := newTestCron(line, clock)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		produced := 0
+		c.Add("job", "*/10 * * * *", tt.policy, func(time.Time) Item {
+			produced++
+			if produced == tt.runs {
+				cancel()
+			}
+			return &testItem{kind: "job", id: produced}
+		})
+		c.Pause()
+		done := make(chan error)
+		go func() { done <- c.Run(ctx) }()
+
+		// 暫停一小時, 期間錯過 6 次執行
+		clock.Advance(time.Hour)
+		c.Resume()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+		case <-time.After(time.Second):
+			cancel()
+			t.Fatalf("policy %d: Run did not finish, produced %d", tt.policy, produced)
+		}
+
+		stats := c.Stats()[0]
+		if stats.Runs != tt.runs || stats.Skipped+stats.CaughtUp != 5 {
+			t.Errorf("policy %d: stats = %+v, want %d runs and 5 missed", tt.policy, stats, tt.runs)
+		}
+		if s := line.Stop(); s.TotalProcessed() != tt.runs {
+			t.Errorf("policy %d: processed = %d, want %d", tt.policy, s.TotalProcessed(), tt.runs)
+		}
+	}
+}
+
+// TestCron_Stopped 驗證流水線停止後 Run 回傳 ErrStopped
+func TestCron_Stopped(t *testing.T) {
+	line := NewAssemblyLine(1)
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}
+	c := newTestCron(line, clock)
+	c.Add("job", "@hourly", SkipMissed, func(time.Time) Item { return &testItem{kind: "job"} })
+
+	if err := c.Run(context.Background()); err != ErrStopped {
+		t.Errorf("Run() error = %v, want ErrStopped", err)
+	}
+}
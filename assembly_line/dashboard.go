@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 員工目前的狀態, 供即時面板顯示
+const (
+	statusIdle       = "閒置"
+	statusProcessing = "處理中"
+	statusChangeover = "切換中"
+	statusThrottled  = "等待限流"
+	statusBreak      = "休息中"
+	statusOffShift   = "不在班"
+	statusCrashed    = "故障"
+	statusOffline    = "離線"
+)
+
+// lener 可選介面, 回傳派發器中尚未被取走的物品數
+type lener interface {
+	Len() int
+}
+
+func (d *channelDispatcher) Len() int {
+	return len(d.ch)
+}
+
+func (d *queueDispatcher) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.items)
+}
+
+func (d *workStealingDispatcher) Len() int {
+	n := 0
+	for _, q := range d.deques {
+		n += int(q.len())
+	}
+	return n
+}
+
+// WithDashboard 執行期間每隔 interval 在 w 上原地重繪即時面板,
+// 並停止逐行打印處理紀錄; w 應為終端機
+func WithDashboard(w io.Writer, interval time.Duration) Option {
+	return func(l *AssemblyLine) {
+		l.dashboard = &dashboard{w: w, interval: interval}
+	}
+}
+
+// dashboard 以 ANSI 控制碼在終端機原地重繪的即時面板
+type dashboard struct {
+	w        io.Writer
+	interval time.Duration
+	// totals 本次執行每種物品的提交數量, 受流水線的 mu 保護
+	totals map[string]int
+	// lines 上一次畫了幾行, 重繪時游標先移回開頭
+	lines int
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+const progressWidth = 30
+
+// start 開始定時重繪
+func (d *dashboard) start(l *AssemblyLine) {
+	d.totals = make(map[string]int)
+	d.lines = 0
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			d.render(l)
+			select {
+			case <-ticker.C:
+			case <-d.stop:
+				d.render(l)
+				return
+			}
+		}
+	}()
+}
+
+// finish 停止重繪並畫出最後一格
+func (d *dashboard) finish() {
+	close(d.stop)
+	<-d.done
+}
+
+// render 畫出一格面板
+func (d *dashboard) render(l *AssemblyLine) {
+	var b strings.Builder
+	if d.lines > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", d.lines)
+	}
+	lines := d.frame(l, time.Now())
+	for _, line := range lines {
+		b.WriteString("\x1b[2K")
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	d.lines = len(lines)
+	io.WriteString(d.w, b.String())
+}
+
+// frame 組出面板每一行的內容
+func (d *dashboard) frame(l *AssemblyLine, now time.Time) []string {
+	queued := 0
+	if q, ok := l.dispatcher.(lener); ok {
+		queued = q.Len()
+	}
+
+	l.mu.Lock()
+	done := make(map[string]int, len(l.types))
+	completed := 0
+	for kind, ts := range l.types {
+		done[kind] = ts.Processed
+		completed += ts.Processed
+	}
+	crashes := l.crashes
+	totals := make(map[string]int, len(d.totals))
+	submitted := 0
+	for kind, n := range d.totals {
+		totals[kind] = n
+		submitted += n
+	}
+	l.mu.Unlock()
+
+	lines := []string{
+		fmt.Sprintf("流水線執行中 %v | 佇列: %d | 未完成: %d | 完成: %d/%d | 故障: %d",
+			now.Sub(l.startTime).Round(time.Millisecond), queued,
+			l.unfinished(), completed, submitted, crashes),
+	}
+	for _, e := range l.employees {
+		state := e.State()
+		line := fmt.Sprintf("  員工 #%-3d %-6s", e.ID, state.Status)
+		if state.Current != "" {
+			line += fmt.Sprintf(" %-12s", state.Current)
+		}
+		if !state.Since.IsZero() {
+			line += fmt.Sprintf(" (%v)", now.Sub(state.Since).Round(time.Millisecond))
+		}
+		line += fmt.Sprintf("  已處理 %d", state.Processed)
+		lines = append(lines, line)
+	}
+
+	kinds := make([]string, 0, len(totals))
+	for kind := range totals {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		lines = append(lines, fmt.Sprintf("  %-8s %s %d/%d",
+			kind, progressBar(done[kind], totals[kind], progressWidth), done[kind], totals[kind]))
+	}
+	return lines
+}
+
+// progressBar 畫出 width 格寬的進度條
+func progressBar(done, total, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = done * width / total
+	}
+	if filled > width {
+		filled = width
+	}
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "]"
+}
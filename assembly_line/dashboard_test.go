@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer 可並發寫入的 bytes.Buffer
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestProgressBar 驗證進度條寬度及比例
+func TestProgressBar(t *testing.T) {
+	tests := []struct {
+		done, total int
+		want        string
+	}{
+		{0, 4, "[░░░░]"},
+		{2, 4, "[██░░]"},
+		{4, 4, "[████]"},
+		{1, 0, "[░░░░]"},
+	}
+	for _, tt := range tests {
+		if got := progressBar(tt.done, tt.total, 4); got != tt.want {
+			t.Errorf("progressBar(%d, %d) = %s, want %s", tt.done, tt.total, got, tt.want)
+		}
+	}
+}
+
+// TestDashboard_Frame 驗證面板顯示員工目前的物品、佇列深度及各類型進度
+func TestDashboard_Frame(t *testing.T) {
+	line := NewAssemblyLine(2, WithDashboard(&syncBuffer{}, time.Hour))
+	items := newTestItems(2, 0, "A", "B")
+	line.startTime = time.Now()
+	line.reset()
+	line.dispatcher = NewFIFODispatcher(len(items))
+	line.pending = int64(len(items)) + 1
+	line.held = 1
+	for _, item := range items[1:] {
+		line.dispatcher.Push(item)
+	}
+	line.record("A", 1, time.Millisecond)
+	line.employees[0].setStatus(statusProcessing, items[0])
+
+	d := line.dashboard
+	d.totals = map[string]int{"A": 2, "B": 2}
+	frame := strings.Join(d.frame(line, time.Now()), "\n")
+
+	for _, want := range []string{"佇列: 3", "未完成: 4", "完成: 1/4", "員工 #1", "處理中", "A #1", "員工 #2", "閒置", "1/2", "0/2"} {
+		if !strings.Contains(frame, want) {
+			t.Errorf("frame does not contain %q:\n%s", want, frame)
+		}
+	}
+}
+
+// TestAssemblyLine_Dashboard 驗證啟用面板時以 ANSI 原地重繪且不打印逐行紀錄
+func TestAssemblyLine_Dashboard(t *testing.T) {
+	var buf syncBuffer
+	line := NewAssemblyLine(2, WithDashboard(&buf, 5*time.Millisecond))
+	line.Run(newTestItems(3, 5*time.Millisecond, "A"))
+
+	out := buf.String()
+	if !strings.Contains(out, "\x1b[2K") || !strings.Contains(out, "\x1b[") {
+		t.Error("dashboard output has no ANSI control sequences")
+	}
+	if !strings.Contains(out, "3/3") {
+		t.Errorf("final frame does not show 3/3:\n%s", out)
+	}
+	if strings.Contains(out, "開始處理") {
+		t.Error("dashboard output contains plain log lines")
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrDuplicate 去重時間窗內已提交過相同冪等鍵的物品
+var ErrDuplicate = errors.New("assembly line: duplicate item")
+
+// Keyed 可選介面, 回傳物品的冪等鍵; 生產者重試時以相同的鍵提交同一件物品
+type Keyed interface {
+	IdempotencyKey() string
+}
+
+// idempotencyKey 物品的冪等鍵, 未實作 Keyed 時為空字串
+func idempotencyKey(item Item) string {
+	if k, ok := item.(Keyed); ok {
+		return k.IdempotencyKey()
+	}
+	return ""
+}
+
+// rekeyedItem 附上信封中冪等鍵的物品, 讓還原後的物品保留原本的鍵;
+// 提交時即拆開, 派發器及員工只會看到原本的物品
+type rekeyedItem struct {
+	Item
+	key string
+}
+
+func (k *rekeyedItem) IdempotencyKey() string {
+	return k.key
+}
+
+func (k *rekeyedItem) Type() string {
+	return itemType(k.Item)
+}
+
+// withKey 物品本身的冪等鍵與 key 不同時, 以 rekeyedItem 附上 key
+func withKey(item Item, key string) Item {
+	if key == "" || idempotencyKey(item) == key {
+		return item
+	}
+	return &rekeyedItem{Item: item, key: key}
+}
+
+// unkeyed 取出 rekeyedItem 包裝的物品
+func unkeyed(item Item) Item {
+	if k, ok := item.(*rekeyedItem); ok {
+		return k.Item
+	}
+	return item
+}
+
+// dedupEntry 冪等鍵第一次出現的時間
+type dedupEntry struct {
+	key string
+	at  time.Time
+}
+
+// dedupWindow 記錄時間窗內出現過的冪等鍵
+type dedupWindow struct {
+	window time.Duration
+	seen   map[string]time.Time
+	// order 依出現時間排列, 供清除過期的鍵
+	order []dedupEntry
+}
+
+func newDedupWindow(window time.Duration) *dedupWindow {
+	return &dedupWindow{window: window, seen: make(map[string]time.Time)}
+}
+
+// claim 登記 key, 時間窗內已出現過時回傳 false
+func (d *dedupWindow) claim(key string, now time.Time) bool {
+	d.expire(now)
+	if _, ok := d.seen[key]; ok {
+		return false
+	}
+	d.seen[key] = now
+	// 永不過期時不需要記錄順序
+	if d.window > 0 {
+		d.order = append(d.order, dedupEntry{key: key, at: now})
+	}
+	return true
+}
+
+// release 取消登記, 用於提交失敗時讓生產者可以重試
+func (d *dedupWindow) release(key string) {
+	delete(d.seen, key)
+}
+
+// expire 清除超過時間窗的鍵, window 為 0 時永不過期
+func (d *dedupWindow) expire(now time.Time) {
+	if d.window <= 0 {
+		return
+	}
+	i := 0
+	for ; i < len(d.order) && now.Sub(d.order[i].at) >= d.window; i++ {
+		e := d.order[i]
+		// 取消後再次登記的鍵以新的時間為準
+		if at, ok := d.seen[e.key]; ok && at.Equal(e.at) {
+			delete(d.seen, e.key)
+		}
+	}
+	d.order = d.order[i:]
+}
+
+// WithDedup 相同冪等鍵的物品在 window 內只接受第一件, 之後的提交回傳 ErrDuplicate;
+// window 為 0 時在流水線存續期間都不接受重複的鍵. 沒有冪等鍵的物品不受影響
+func WithDedup(window time.Duration) Option {
+	return func(l *AssemblyLine) {
+		l.dedup = newDedupWindow(window)
+	}
+}
+
+// claimKey 登記物品的冪等鍵, 重複時計入統計並回傳 ErrDuplicate
+func (l *AssemblyLine) claimKey(key string) error {
+	if l.dedup == nil || key == "" {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.dedup.claim(key, time.Now()) {
+		l.duplicates++
+		return ErrDuplicate
+	}
+	return nil
+}
+
+// releaseKey 提交失敗時取消登記冪等鍵
+func (l *AssemblyLine) releaseKey(key string) {
+	if l.dedup == nil || key == "" {
+		return
+	}
+	l.mu.Lock()
+	l.dedup.release(key)
+	l.mu.Unlock()
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// keyedItem 帶有冪等鍵的測試物品
+type keyedItem struct {
+	testItem
+	key string
+}
+
+func (i *keyedItem) IdempotencyKey() string { return i.key }
+
+// TestDedupWindow 驗證時間窗內拒絕重複的鍵, 過期或取消後可再次登記
+func TestDedupWindow(t *testing.T) {
+	d := newDedupWindow(time.Second)
+	t0 := time.Unix(0, 0)
+
+	if !d.claim("a", t0) {
+		t.Fatal("claim(a) = false, want true")
+	}
+	if d.claim("a", t0.Add(999*time.Millisecond)) {
+		t.Error("claim(a) within window = true, want false")
+	}
+	if !d.claim("b", t0.Add(500*time.Millisecond)) {
+		t.Error("claim(b) = false, want true")
+	}
+	if !d.claim("a", t0.Add(time.Second)) {
+		t.Error("claim(a) after window = false, want true")
+	}
+	// b 仍在時間窗內
+	if d.claim("b", t0.Add(1200*time.Millisecond)) {
+		t.Error("claim(b) within window = true, want false")
+	}
+
+	d.release("b")
+	if !d.claim("b", t0.Add(1300*time.Millisecond)) {
+		t.Error("claim(b) after release = false, want true")
+	}
+	// 舊的 b 紀錄過期時不影響重新登記的 b
+	if d.claim("b", t0.Add(1600*time.Millisecond)) {
+		t.Error("claim(b) after old entry expired = true, want false")
+	}
+
+	forever := newDedupWindow(0)
+	forever.claim("x", t0)
+	if forever.claim("x", t0.Add(24*time.Hour)) {
+		t.Error("claim(x) with zero window = true, want false")
+	}
+	if len(forever.order) != 0 {
+		t.Errorf("order with zero window = %v, want empty", forever.order)
+	}
+}
+
+// TestAssemblyLine_Dedup 驗證重複的冪等鍵只處理一次並計入統計, 沒有鍵的物品不受影響
+func TestAssemblyLine_Dedup(t *testing.T) {
+	var processed int32
+	newItem := func(key string) Item {
+		return &keyedItem{testItem: testItem{kind: "keyed", d: time.Millisecond, processed: &processed}, key: key}
+	}
+	items := []Item{newItem("a"), newItem("b"), newItem("a"), newItem(""), newItem(""), newItem("b"), newItem("a")}
+
+	stats := NewAssemblyLine(2, WithDedup(time.Minute)).Run(items)
+	if got := atomic.LoadInt32(&processed); got != 4 {
+		t.Errorf("processed = %d, want 4", got)
+	}
+	if stats.Duplicates != 3 || stats.Submitted != 4 {
+		t.Errorf("Duplicates = %d, Submitted = %d, want 3 and 4", stats.Duplicates, stats.Submitted)
+	}
+
+	// 沒有啟用去重時全部處理
+	processed = 0
+	if stats := NewAssemblyLine(2).Run(items); stats.Duplicates != 0 || atomic.LoadInt32(&processed) != 7 {
+		t.Errorf("without dedup: Duplicates = %d, processed = %d, want 0 and 7", stats.Duplicates, processed)
+	}
+}
+
+// TestAssemblyLine_DedupWindowExpires 驗證時間窗過後相同的鍵可再次提交
+func TestAssemblyLine_DedupWindowExpires(t *testing.T) {
+	line := NewAssemblyLine(1, WithDedup(50*time.Millisecond))
+	line.Start()
+	if err := line.Submit(&keyedItem{testItem: testItem{kind: "keyed", id: 1}, key: "k"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if err := line.Submit(&keyedItem{testItem: testItem{kind: "keyed", id: 2}, key: "k"}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Submit() duplicate error = %v, want ErrDuplicate", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if err := line.Submit(&keyedItem{testItem: testItem{kind: "keyed", id: 3}, key: "k"}); err != nil {
+		t.Errorf("Submit() after window error = %v", err)
+	}
+	stats := line.Stop()
+	if stats.TotalProcessed() != 2 || stats.Duplicates != 1 {
+		t.Errorf("processed = %d, Duplicates = %d, want 2 and 1", stats.TotalProcessed(), stats.Duplicates)
+	}
+}
+
+// TestEnvelope_Key 驗證信封帶上物品的冪等鍵
+func TestEnvelope_Key(t *testing.T) {
+	registry := NewItemRegistry()
+	registry.Register(ItemType{
+		Name:   "keyed",
+		Encode: func(item Item) (int, json.RawMessage, error) { return item.(*keyedItem).id, nil, nil },
+		Decode: func(id int, _ json.RawMessage) (Item, error) { return &testItem{kind: "keyed", id: id}, nil },
+	})
+	env, err := registry.Wrap(&keyedItem{testItem: testItem{kind: "keyed", id: 1}, key: "order-42"})
+	if err != nil {
+		t.Fatalf("Wrap() error = %v", err)
+	}
+	if env.Key != "order-42" {
+		t.Errorf("Key = %q, want order-42", env.Key)
+	}
+}
+
+// testItemRegistry 以編號保存 testItem 的註冊表
+func testItemRegistry(kind string) *ItemRegistry {
+	registry := NewItemRegistry()
+	registry.Register(ItemType{
+		Name:   kind,
+		Encode: func(item Item) (int, json.RawMessage, error) { return item.(*testItem).id, nil, nil },
+		Decode: func(id int, _ json.RawMessage) (Item, error) { return &testItem{kind: kind, id: id}, nil },
+	})
+	return registry
+}
+
+// TestEnvelope_UnwrapKey 驗證還原的物品保留信封的冪等鍵, 再次裝入信封時鍵不變
+func TestEnvelope_UnwrapKey(t *testing.T) {
+	registry := testItemRegistry("A")
+	item, err := registry.Unwrap(Envelope{Type: "A", ID: 1, Key: "order-42"})
+	if err != nil {
+		t.Fatalf("Unwrap() error = %v", err)
+	}
+	if got := idempotencyKey(item); got != "order-42" {
+		t.Errorf("idempotencyKey() = %q, want order-42", got)
+	}
+	if got := itemType(item); got != "A" {
+		t.Errorf("itemType() = %q, want A", got)
+	}
+	env, err := registry.Wrap(item)
+	if err != nil {
+		t.Fatalf("Wrap() error = %v", err)
+	}
+	if env.Type != "A" || env.ID != 1 || env.Key != "order-42" {
+		t.Errorf("Wrap() = %+v, want A #1 with key order-42", env)
+	}
+
+	if item, _ := registry.Unwrap(Envelope{Type: "A", ID: 2}); item.(*testItem).id != 2 {
+		t.Errorf("Unwrap() without key = %v, want the decoded item", item)
+	}
+}
+
+// TestAssemblyLine_WALReplayDedup 驗證由日誌恢復的物品保留冪等鍵, 重複提交仍會被略過
+func TestAssemblyLine_WALReplayDedup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "line.wal")
+	codec := JSONCodec{Registry: testItemRegistry("A")}
+	w, _, err := OpenWAL(path, codec)
+	if err != nil {
+		t.Fatalf("OpenWAL() error = %v", err)
+	}
+	w.Submit(withKey(&testItem{kind: "A", id: 1}, "order-1"))
+	w.Close()
+
+	w, pending, err := OpenWAL(path, codec)
+	if err != nil {
+		t.Fatalf("OpenWAL() error = %v", err)
+	}
+	defer w.Close()
+	if len(pending) != 1 || idempotencyKey(pending[0]) != "order-1" {
+		t.Fatalf("pending = %v, want one item keyed order-1", pending)
+	}
+	retry := withKey(&testItem{kind: "A", id: 1}, "order-1")
+	stats := NewAssemblyLine(1, WithWAL(w), WithDedup(0)).Run(append(pending, retry))
+	if stats.TotalProcessed() != 1 || stats.Duplicates != 1 || w.Pending() != 0 {
+		t.Errorf("TotalProcessed() = %d, Duplicates = %d, Pending() = %d, want 1, 1 and 0",
+			stats.TotalProcessed(), stats.Duplicates, w.Pending())
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Deferred 可選介面, 物品不得早於 NotBefore 開始處理
+type Deferred interface {
+	NotBefore() time.Time
+}
+
+// Delayed 可選介面, 物品在提交後經過 Delay 才能開始處理
+type Delayed interface {
+	Delay() time.Duration
+}
+
+// releaseAt 物品可以開始處理的時間, 兩個介面都實作時取較晚者
+func releaseAt(item Item, submitted time.Time) (time.Time, bool) {
+	var at time.Time
+	if d, ok := item.(Deferred); ok {
+		at = d.NotBefore()
+	}
+	if d, ok := item.(Delayed); ok && d.Delay() > 0 {
+		if t := submitted.Add(d.Delay()); t.After(at) {
+			at = t
+		}
+	}
+	return at, !at.IsZero()
+}
+
+// delayEntry 延遲佇列中的物品及其放行時間
+type delayEntry struct {
+	item Item
+	at   time.Time
+}
+
+// delayHeap 依放行時間排序的最小堆積
+type delayHeap []delayEntry
+
+func (h delayHeap) Len() int           { return len(h) }
+func (h delayHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h delayHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *delayHeap) Push(x any)        { *h = append(*h, x.(delayEntry)) }
+func (h *delayHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = delayEntry{}
+	*h = old[:len(old)-1]
+	return e
+}
+
+// delayQueue 位於派發器前的延遲佇列, 以計時器在放行時間到時交給 release
+type delayQueue struct {
+	mu      sync.Mutex
+	entries delayHeap
+	wake    chan struct{}
+	release func(Item)
+}
+
+func newDelayQueue(release func(Item)) *delayQueue {
+	return &delayQueue{
+		wake:    make(chan struct{}, 1),
+		release: release,
+	}
+}
+
+// add 加入物品, at 已過時會在下一輪立即放行
+func (q *delayQueue) add(item Item, at time.Time) {
+	q.mu.Lock()
+	heap.Push(&q.entries, delayEntry{item: item, at: at})
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// len 尚未放行的物品數
+func (q *delayQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries)
+}
+
+// run 放行到期的物品並等待下一件到期, 直到 stop 關閉
+func (q *delayQueue) run(stop <-chan struct{}) {
+	for {
+		wait := time.Duration(-1)
+		q.mu.Lock()
+		for len(q.entries) > 0 {
+			if d := time.Until(q.entries[0].at); d > 0 {
+				wait = d
+				break
+			}
+			e := heap.Pop(&q.entries).(delayEntry)
+			q.mu.Unlock()
+			q.release(e.item)
+			q.mu.Lock()
+		}
+		q.mu.Unlock()
+
+		var timer *time.Timer
+		var fire <-chan time.Time
+		if wait >= 0 {
+			timer = time.NewTimer(wait)
+			fire = timer.C
+		}
+		select {
+		case <-fire:
+		case <-q.wake:
+		case <-stop:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+	}
+}
+
+// deferItem 將物品放入延遲佇列, 放行時間到時才交給派發器
+func (l *AssemblyLine) deferItem(s *submission, at time.Time) {
+	l.mu.Lock()
+	s.due = at
+	l.delayed++
+	l.mu.Unlock()
+	l.delays.add(s, at)
+}
+
+// release 放行到期的物品; 放行時有員工閒置就不算延誤,
+// 之後開始處理的落差只是計時器及派發本身的誤差
+func (l *AssemblyLine) release(s *submission) {
+	fmt.Fprintf(l.out, "[%s] %s 到達排程時間, 開始派發\n", time.Now().Format(timeLayout), s.String())
+	for _, e := range l.employees {
+		if e.State().Status == statusIdle {
+			l.mu.Lock()
+			s.due = time.Time{}
+			l.mu.Unlock()
+			break
+		}
+	}
+	l.dispatcher.Push(s)
+}
+
+// recordLateness 物品第一次開始處理時, 記錄比放行時間晚了多久;
+// 只有放行時沒有員工閒置的物品才會計入
+func (l *AssemblyLine) recordLateness(s *submission, start time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	at := s.due
+	if at.IsZero() {
+		return
+	}
+	s.due = time.Time{}
+	late := start.Sub(at)
+	if late <= 0 {
+		return
+	}
+	l.late++
+	l.lateness += late
+	if late > l.maxLateness {
+		l.maxLateness = late
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// delayedItem 提交後需延遲才能開始的測試物品
+type delayedItem struct {
+	testItem
+	delay time.Duration
+}
+
+func (i *delayedItem) Delay() time.Duration {
+	return i.delay
+}
+
+// deferredItem 不得早於指定時間開始的測試物品
+type deferredItem struct {
+	testItem
+	at time.Time
+}
+
+func (i *deferredItem) NotBefore() time.Time {
+	return i.at
+}
+
+// TestReleaseAt 驗證物品的放行時間
+func TestReleaseAt(t *testing.T) {
+	submitted := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	later := submitted.Add(time.Minute)
+
+	if _, ok := releaseAt(&testItem{}, submitted); ok {
+		t.Error("releaseAt(testItem) ok = true, want false")
+	}
+	if at, _ := releaseAt(&delayedItem{delay: time.Second}, submitted); !at.Equal(submitted.Add(time.Second)) {
+		t.Errorf("releaseAt(delayedItem) = %v, want submitted + 1s", at)
+	}
+	if at, _ := releaseAt(&deferredItem{at: later}, submitted); !at.Equal(later) {
+		t.Errorf("releaseAt(deferredItem) = %v, want %v", at, later)
+	}
+}
+
+// TestDelayQueue_Order 驗證依放行時間先後放行, 運行中加入的物品也會喚醒計時器
+func TestDelayQueue_Order(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	done := make(chan struct{})
+	q := newDelayQueue(func(item Item) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, item.String())
+		if len(got) == 4 {
+			close(done)
+		}
+	})
+
+	now := time.Now()
+	q.add(&testItem{kind: "C"}, now.Add(60*time.Millisecond))
+	q.add(&testItem{kind: "A"}, now.Add(20*time.Millisecond))
+	q.add(&testItem{kind: "B"}, now.Add(40*time.Millisecond))
+	stop := make(chan struct{})
+	defer close(stop)
+	go q.run(stop)
+	q.add(&testItem{kind: "Z"}, now.Add(-time.Millisecond))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("released %v before timeout, want 4 items", got)
+	}
+	want := "Z #0,A #0,B #0,C #0"
+	if s := strings.Join(got, ","); s != want {
+		t.Errorf("release order = %s, want %s", s, want)
+	}
+	if q.len() != 0 {
+		t.Errorf("len() = %d, want 0", q.len())
+	}
+}
+
+// TestAssemblyLine_Delayed 驗證延遲物品不會提早開始
+func TestAssemblyLine_Delayed(t *testing.T) {
+	delayed := &delayedItem{testItem: testItem{kind: "A", id: 1, d: time.Millisecond}, delay: 100 * time.Millisecond}
+	items := []Item{delayed, &testItem{kind: "B", id: 1, d: time.Millisecond}}
+	line := NewAssemblyLine(2)
+	stats := line.Run(items)
+
+	if got := stats.TotalProcessed(); got != 2 {
+		t.Fatalf("TotalProcessed = %d, want 2", got)
+	}
+	for _, span := range stats.Timeline {
+		if span.Item == delayed.String() && span.Start.Sub(stats.Start) < 100*time.Millisecond {
+			t.Errorf("delayed item started after %v, want at least 100ms", span.Start.Sub(stats.Start))
+		}
+	}
+	if stats.Delayed != 1 || stats.Late != 0 {
+		t.Errorf("Delayed = %d, Late = %d, want 1, 0", stats.Delayed, stats.Late)
+	}
+}
+
+// TestAssemblyLine_Lateness 驗證員工忙碌時延誤開始的時間會被記錄
+func TestAssemblyLine_Lateness(t *testing.T) {
+	start := time.Now()
+	items := []Item{
+		&testItem{kind: "A", id: 1, d: 150 * time.Millisecond},
+		&deferredItem{testItem: testItem{kind: "B", id: 1, d: time.Millisecond}, at: start.Add(50 * time.Millisecond)},
+	}
+	line := NewAssemblyLine(1)
+	stats := line.Run(items)
+
+	if stats.Late != 1 {
+		t.Fatalf("Late = %d, want 1", stats.Late)
+	}
+	if stats.MaxLateness < 90*time.Millisecond || stats.MaxLateness > 300*time.Millisecond {
+		t.Errorf("MaxLateness = %v, want about 100ms", stats.MaxLateness)
+	}
+
+	var buf bytes.Buffer
+	stats.Print(&buf)
+	if !strings.Contains(buf.String(), "排程物品: 1 件, 延誤開始: 1 件") {
+		t.Errorf("output does not contain lateness:\n%s", buf.String())
+	}
+}
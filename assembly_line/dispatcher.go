@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDrained 派發器已關閉且沒有剩餘物品
+var ErrDrained = errors.New("dispatcher: drained")
+
+// Dispatcher 決定員工下一件要處理的物品
+type Dispatcher interface {
+	// Push 加入待處理物品
+	Push(item Item)
+	// Close 表示不會再加入新物品
+	Close()
+	// Next 為員工取出下一件物品, 已關閉且清空時回傳 ErrDrained
+	Next(ctx context.Context, e *Employee) (Item, error)
+}
+
+// DispatcherFactory 每次執行時為流水線創建派發器
+type DispatcherFactory func(employees []*Employee) Dispatcher
+
+// channelDispatcher 所有員工共用一個 channel, 先進先出
+type channelDispatcher struct {
+	ch chan Item
+}
+
+// NewFIFODispatcher 創建共用 channel 的先進先出派發器
+func NewFIFODispatcher(capacity int) Dispatcher {
+	return &channelDispatcher{ch: make(chan Item, capacity)}
+}
+
+func (d *channelDispatcher) Push(item Item) {
+	d.ch <- item
+}
+
+func (d *channelDispatcher) Close() {
+	close(d.ch)
+}
+
+func (d *channelDispatcher) Next(ctx context.Context, e *Employee) (Item, error) {
+	select {
+	case item, ok := <-d.ch:
+		if !ok {
+			return nil, ErrDrained
+		}
+		return item, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// queueDispatcher 以共用佇列保存物品, 由 pick 決定交給員工哪一件
+type queueDispatcher struct {
+	mu      sync.Mutex
+	items   []Item
+	closed  bool
+	changed chan struct{}
+	pick    func(items []Item, e *Employee) int
+}
+
+func newQueueDispatcher(pick func(items []Item, e *Employee) int) *queueDispatcher {
+	return &queueDispatcher{
+		changed: make(chan struct{}),
+		pick:    pick,
+	}
+}
+
+func (d *queueDispatcher) Push(item Item) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.items = append(d.items, item)
+	d.broadcast()
+}
+
+func (d *queueDispatcher) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	d.broadcast()
+}
+
+// broadcast 喚醒所有等待中的員工, 呼叫時需持有鎖
+func (d *queueDispatcher) broadcast() {
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+func (d *queueDispatcher) Next(ctx context.Context, e *Employee) (Item, error) {
+	for {
+		d.mu.Lock()
+		if len(d.items) > 0 {
+			i := d.pick(d.items, e)
+			item := d.items[i]
+			if i == 0 {
+				d.items[0] = nil
+				d.items = d.items[1:]
+			} else {
+				d.items = append(d.items[:i], d.items[i+1:]...)
+			}
+			d.mu.Unlock()
+			return item, nil
+		}
+		if d.closed {
+			d.mu.Unlock()
+			return nil, ErrDrained
+		}
+		changed := d.changed
+		d.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// NewAffinityDispatcher 優先派給員工與上一件相同類型的物品, 減少切換成本
+func NewAffinityDispatcher(employees []*Employee) Dispatcher {
+	return newQueueDispatcher(func(items []Item, e *Employee) int {
+		if e.lastType == "" {
+			return 0
+		}
+		for i, item := range items {
+			if itemType(item) == e.lastType {
+				return i
+			}
+		}
+		return 0
+	})
+}
+
+// estimate 物品預估的處理時間, 未實作 Estimator 時為 0
+func estimate(item Item) time.Duration {
+	if est, ok := item.(Estimator); ok {
+		return est.EstimatedDuration()
+	}
+	return 0
+}
+
+// NewSPTDispatcher 最短處理時間優先 (shortest processing time),
+// 依 EstimatedDuration 派發, 可降低平均等待時間;
+// 未實作 Estimator 的物品視為 0, 最先派發; 預估相同時先進先出
+func NewSPTDispatcher(employees []*Employee) Dispatcher {
+	return newQueueDispatcher(func(items []Item, e *Employee) int {
+		best := 0
+		for i, item := range items {
+			if estimate(item) < estimate(items[best]) {
+				best = i
+			}
+		}
+		return best
+	})
+}
+
+// NewLPTDispatcher 最長處理時間優先 (longest processing time),
+// 先處理耗時的物品, 最後以短的物品填補空檔, 可縮短總完工時間;
+// 未實作 Estimator 的物品視為 0, 最後派發; 預估相同時先進先出
+func NewLPTDispatcher(employees []*Employee) Dispatcher {
+	return newQueueDispatcher(func(items []Item, e *Employee) int {
+		best := 0
+		for i, item := range items {
+			if estimate(item) > estimate(items[best]) {
+				best = i
+			}
+		}
+		return best
+	})
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// drain 以同一位員工取出派發器中所有物品
+func drain(t *testing.T, d Dispatcher, e *Employee) []Item {
+	t.Helper()
+	var got []Item
+	for {
+		item, err := d.Next(context.Background(), e)
+		if errors.Is(err, ErrDrained) {
+			return got
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		got = append(got, item)
+	}
+}
+
+// TestFIFODispatcher 驗證先進先出順序
+func TestFIFODispatcher(t *testing.T) {
+	items := newTestItems(3, 0, "A", "B")
+	d := NewFIFODispatcher(len(items))
+	for _, item := range items {
+		d.Push(item)
+	}
+	d.Close()
+
+	got := drain(t, d, &Employee{ID: 1})
+	if len(got) != len(items) {
+		t.Fatalf("got %d items, want %d", len(got), len(items))
+	}
+	for i := range items {
+		if got[i] != items[i] {
+			t.Errorf("item %d = %s, want %s", i, got[i], items[i])
+		}
+	}
+}
+
+// TestDispatcher_NextTimeout 驗證沒有物品時 Next 會在 context 結束後返回
+func TestDispatcher_NextTimeout(t *testing.T) {
+	dispatchers := map[string]Dispatcher{
+		"fifo":     NewFIFODispatcher(1),
+		"affinity": NewAffinityDispatcher(nil),
+	}
+	for name, d := range dispatchers {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			if _, err := d.Next(ctx, &Employee{ID: 1}); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Next() error = %v, want DeadlineExceeded", err)
+			}
+		})
+	}
+}
+
+// TestQueueDispatcher_WakeOnPush 驗證等待中的員工會被新物品喚醒
+func TestQueueDispatcher_WakeOnPush(t *testing.T) {
+	d := NewAffinityDispatcher(nil)
+	item := &testItem{kind: "A", id: 1}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		d.Push(item)
+	}()
+
+	got, err := d.Next(context.Background(), &Employee{ID: 1})
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if got != item {
+		t.Errorf("Next() = %s, want %s", got, item)
+	}
+}
+
+// TestAffinityDispatcher 驗證優先派發與上一件相同類型的物品
+func TestAffinityDispatcher(t *testing.T) {
+	d := NewAffinityDispatcher(nil)
+	for _, kind := range []string{"A", "B", "A", "C", "B"} {
+		d.Push(&testItem{kind: kind})
+	}
+	d.Close()
+
+	e := &Employee{ID: 1}
+	var kinds []string
+	for {
+		item, err := d.Next(context.Background(), e)
+		if err != nil {
+			break
+		}
+		e.lastType = itemType(item)
+		kinds = append(kinds, e.lastType)
+	}
+
+	want := []string{"A", "A", "B", "B", "C"}
+	if len(kinds) != len(want) {
+		t.Fatalf("got %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("got %v, want %v", kinds, want)
+		}
+	}
+}
+
+// plainItem 未實作 Estimator 的物品
+type plainItem struct{ id int }
+
+func (i *plainItem) Process()       {}
+func (i *plainItem) String() string { return fmt.Sprintf("plain #%d", i.id) }
+
+// TestProcessingTimeDispatchers 驗證依預估處理時間排序, 相同時先進先出
+func TestProcessingTimeDispatchers(t *testing.T) {
+	short1 := &testItem{kind: "A", id: 1, d: 100 * time.Millisecond}
+	short2 := &testItem{kind: "A", id: 2, d: 100 * time.Millisecond}
+	mid := &testItem{kind: "B", id: 1, d: 150 * time.Millisecond}
+	long := &testItem{kind: "C", id: 1, d: 200 * time.Millisecond}
+	unknown := &plainItem{id: 1}
+	items := []Item{mid, short1, unknown, long, short2}
+
+	tests := []struct {
+		name string
+		new  DispatcherFactory
+		want []Item
+	}{
+		{"SPT", NewSPTDispatcher, []Item{unknown, short1, short2, mid, long}},
+		{"LPT", NewLPTDispatcher, []Item{long, mid, short1, short2, unknown}},
+	}
+	for _, tt := range tests {
+		d := tt.new(nil)
+		for _, item := range items {
+			d.Push(item)
+		}
+		d.Close()
+
+		got := drain(t, d, &Employee{ID: 1})
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s order = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
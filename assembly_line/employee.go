@@ -0,0 +1,106 @@
+package main
+
+import (
+	"time"
+)
+
+// EmployeeState 員工某一時刻的狀態快照, 計數及時間為員工建立以來的累計
+type EmployeeState struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	// Current 正在處理的物品, 沒有時為空字串
+	Current string `json:"current,omitempty"`
+	// Since 進入目前狀態的時間
+	Since     time.Time `json:"since"`
+	Processed int       `json:"processed"`
+	// ProcessedByType 各類型處理完成的件數, 與其他快照共用, 不可修改
+	ProcessedByType map[string]int `json:"processed_by_type,omitempty"`
+	Busy            time.Duration  `json:"busy"`
+	Crashes         int            `json:"crashes"`
+	// LastActive 最後一次開始或完成處理的時間
+	LastActive time.Time `json:"last_active"`
+}
+
+// State 回傳員工目前狀態的快照, 可由任意 goroutine 呼叫, 不需加鎖
+func (e *Employee) State() EmployeeState {
+	s := e.state.Load()
+	if s == nil {
+		return EmployeeState{ID: e.ID, Status: statusIdle}
+	}
+	return *s
+}
+
+// update 複製目前的快照, 以 fn 修改後整份替換;
+// 與其他 goroutine 同時更新時重試, 讀取者永遠看到一致的快照
+func (e *Employee) update(fn func(s *EmployeeState)) {
+	for {
+		old := e.state.Load()
+		next := EmployeeState{Status: statusIdle}
+		if old != nil {
+			next = *old
+		}
+		next.ID = e.ID
+		fn(&next)
+		if e.state.CompareAndSwap(old, &next) {
+			return
+		}
+	}
+}
+
+// setStatus 更新員工狀態, item 為正在處理的物品 (可為 nil)
+func (e *Employee) setStatus(status string, item Item) {
+	now := time.Now()
+	e.update(func(s *EmployeeState) {
+		s.Status = status
+		s.Current = ""
+		if item != nil {
+			s.Current = item.String()
+		}
+		s.Since = now
+		if status == statusProcessing {
+			s.LastActive = now
+		}
+	})
+}
+
+// setIdle 處理完一批物品後回到閒置, 並累計忙碌時間
+func (e *Employee) setIdle(busy time.Duration) {
+	now := time.Now()
+	e.update(func(s *EmployeeState) {
+		s.Status = statusIdle
+		s.Current = ""
+		s.Since = now
+		s.Busy += busy
+	})
+}
+
+// recordCompleted 記錄完成一件 kind 類型的物品
+func (e *Employee) recordCompleted(kind string) {
+	now := time.Now()
+	e.update(func(s *EmployeeState) {
+		byType := make(map[string]int, len(s.ProcessedByType)+1)
+		for k, n := range s.ProcessedByType {
+			byType[k] = n
+		}
+		byType[kind]++
+		s.ProcessedByType = byType
+		s.Processed++
+		s.LastActive = now
+	})
+}
+
+// recordCrash 記錄一次故障
+func (e *Employee) recordCrash() {
+	e.update(func(s *EmployeeState) {
+		s.Crashes++
+	})
+}
+
+// EmployeeStates 所有員工目前的狀態快照, 執行期間可隨時呼叫
+func (l *AssemblyLine) EmployeeStates() []EmployeeState {
+	states := make([]EmployeeState, len(l.employees))
+	for i, e := range l.employees {
+		states[i] = e.State()
+	}
+	return states
+}
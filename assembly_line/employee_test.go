@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestEmployee_StateSnapshot 驗證多個 goroutine 同時更新及讀取時快照保持一致
+func TestEmployee_StateSnapshot(t *testing.T) {
+	e := &Employee{ID: 7}
+	if s := e.State(); s.ID != 7 || s.Status != statusIdle || s.Processed != 0 {
+		t.Errorf("initial State() = %+v, want idle employee 7", s)
+	}
+
+	const writers, each = 8, 200
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	go func() {
+		// 讀取者看到的總數必須等於各類型的加總
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			s := e.State()
+			sum := 0
+			for _, n := range s.ProcessedByType {
+				sum += n
+			}
+			if sum != s.Processed {
+				t.Errorf("snapshot Processed = %d, by type sum = %d", s.Processed, sum)
+				return
+			}
+		}
+	}()
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			kind := []string{"a", "b"}[i%2]
+			for j := 0; j < each; j++ {
+				e.recordCompleted(kind)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(stop)
+
+	s := e.State()
+	if s.Processed != writers*each || e.GetCount() != writers*each {
+		t.Errorf("Processed = %d, GetCount() = %d, want %d", s.Processed, e.GetCount(), writers*each)
+	}
+	if s.ProcessedByType["a"] != writers*each/2 || s.ProcessedByType["b"] != writers*each/2 {
+		t.Errorf("ProcessedByType = %v, want %d each", s.ProcessedByType, writers*each/2)
+	}
+	// 先前取得的快照不受之後的更新影響
+	e.recordCompleted("a")
+	if s.ProcessedByType["a"] != writers*each/2 {
+		t.Error("earlier snapshot changed after update")
+	}
+}
+
+// TestAssemblyLine_EmployeeStates 驗證執行後的員工狀態與統計一致
+func TestAssemblyLine_EmployeeStates(t *testing.T) {
+	line := NewAssemblyLine(2, WithFailures(Failure{AtAttempts: []int{1}, Recovery: time.Millisecond}))
+	start := time.Now()
+	stats := line.Run(newTestItems(3, 5*time.Millisecond, "a", "b"))
+
+	states := line.EmployeeStates()
+	total, crashes := 0, 0
+	for i, s := range states {
+		if s.ID != i+1 || s.Status != statusIdle || s.Current != "" {
+			t.Errorf("state %d = %+v, want idle employee %d", i, s, i+1)
+		}
+		if s.Processed != stats.Employees[i].Processed {
+			t.Errorf("employee %d Processed = %d, stats %d", s.ID, s.Processed, stats.Employees[i].Processed)
+		}
+		if s.Processed > 0 && (s.Busy <= 0 || s.LastActive.Before(start)) {
+			t.Errorf("employee %d Busy = %v, LastActive = %v, want activity", s.ID, s.Busy, s.LastActive)
+		}
+		total += s.ProcessedByType["a"] + s.ProcessedByType["b"]
+		crashes += s.Crashes
+	}
+	if total != 6 || crashes != stats.Crashes || crashes != 1 {
+		t.Errorf("by type total = %d, crashes = %d (stats %d), want 6 and 1", total, crashes, stats.Crashes)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownItemType 物品類型未註冊
+var ErrUnknownItemType = errors.New("item: unknown type")
+
+// Envelope 可跨行程傳遞的物品, Payload 為物品類型自訂的 JSON 內容
+type Envelope struct {
+	Type     string          `json:"type"`
+	ID       int             `json:"id"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	Priority int             `json:"priority,omitempty"`
+	// Attempts 已嘗試處理的次數, 由傳遞物品的一方維護
+	Attempts int `json:"attempts,omitempty"`
+	// Key 冪等鍵, 生產者重試時沿用相同的鍵, 啟用 WithDedup 時重複的提交會被略過
+	Key string `json:"key,omitempty"`
+}
+
+// Prioritized 可選介面, 回傳物品的優先順序, 數值越大越優先
+type Prioritized interface {
+	Priority() int
+}
+
+// ItemType 已註冊的物品類型
+type ItemType struct {
+	Name string
+	// Encode 取出物品的編號及內容
+	Encode func(item Item) (id int, payload json.RawMessage, err error)
+	// Decode 由編號及內容還原物品
+	Decode func(id int, payload json.RawMessage) (Item, error)
+}
+
+// ItemRegistry 物品類型名稱與編碼方式的對應
+type ItemRegistry struct {
+	mu    sync.RWMutex
+	types map[string]ItemType
+}
+
+// NewItemRegistry 創建空的物品類型註冊表
+func NewItemRegistry() *ItemRegistry {
+	return &ItemRegistry{types: make(map[string]ItemType)}
+}
+
+// BuiltinItems 已註冊 Item1, Item2, Item3 的註冊表
+func BuiltinItems() *ItemRegistry {
+	r := NewItemRegistry()
+	r.Register(builtinItemType("Item1", func(id int) Item { return &Item1{ID: id} }))
+	r.Register(builtinItemType("Item2", func(id int) Item { return &Item2{ID: id} }))
+	r.Register(builtinItemType("Item3", func(id int) Item { return &Item3{ID: id} }))
+	return r
+}
+
+// builtinItemType 只有編號、沒有其他內容的內建物品類型
+func builtinItemType(name string, newItem func(id int) Item) ItemType {
+	return ItemType{
+		Name: name,
+		Encode: func(item Item) (int, json.RawMessage, error) {
+			switch i := item.(type) {
+			case *Item1:
+				return i.ID, nil, nil
+			case *Item2:
+				return i.ID, nil, nil
+			case *Item3:
+				return i.ID, nil, nil
+			}
+			return 0, nil, fmt.Errorf("%T is not a %s", item, name)
+		},
+		Decode: func(id int, _ json.RawMessage) (Item, error) {
+			return newItem(id), nil
+		},
+	}
+}
+
+// Register 註冊物品類型, 名稱須與 itemType 回傳的類型名稱一致
+func (r *ItemRegistry) Register(t ItemType) error {
+	if t.Name == "" || t.Encode == nil || t.Decode == nil {
+		return errors.New("item: type needs a name, Encode and Decode")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.types[t.Name]; ok {
+		return fmt.Errorf("item: type %q already registered", t.Name)
+	}
+	r.types[t.Name] = t
+	return nil
+}
+
+// Names 已註冊的類型名稱
+func (r *ItemRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.types))
+	for name := range r.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup 取得已註冊的類型, 未註冊時回傳 ErrUnknownItemType
+func (r *ItemRegistry) lookup(name string) (ItemType, error) {
+	r.mu.RLock()
+	t, ok := r.types[name]
+	r.mu.RUnlock()
+	if !ok {
+		return ItemType{}, fmt.Errorf("%w %q (registered: %s)", ErrUnknownItemType, name, strings.Join(r.Names(), ", "))
+	}
+	return t, nil
+}
+
+// Wrap 將物品裝入信封
+func (r *ItemRegistry) Wrap(item Item) (Envelope, error) {
+	key := idempotencyKey(item)
+	item = unkeyed(item)
+	t, err := r.lookup(itemType(item))
+	if err != nil {
+		return Envelope{}, err
+	}
+	id, payload, err := t.Encode(item)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("item: encode %s: %w", item.String(), err)
+	}
+	env := Envelope{Type: t.Name, ID: id, Payload: payload, Key: key}
+	if p, ok := item.(Prioritized); ok {
+		env.Priority = p.Priority()
+	}
+	return env, nil
+}
+
+// Unwrap 由信封還原物品, 信封的冪等鍵與物品本身的不同時仍以信封的為準
+func (r *ItemRegistry) Unwrap(env Envelope) (Item, error) {
+	t, err := r.lookup(env.Type)
+	if err != nil {
+		return nil, err
+	}
+	item, err := t.Decode(env.ID, env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("item: decode %s #%d: %w", env.Type, env.ID, err)
+	}
+	return withKey(item, env.Key), nil
+}
+
+// JSONCodec 以 JSON 編碼信封, 同時實作 ItemCodec
+type JSONCodec struct {
+	Registry *ItemRegistry
+}
+
+// EncodeEnvelope 將信封編碼為 JSON
+func (c JSONCodec) EncodeEnvelope(env Envelope) ([]byte, error) {
+	return json.Marshal(env)
+}
+
+// DecodeEnvelope 由 JSON 解碼信封, 拒絕未知欄位
+func (c JSONCodec) DecodeEnvelope(data []byte) (Envelope, error) {
+	var env Envelope
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&env); err != nil {
+		return Envelope{}, fmt.Errorf("item: decode envelope: %w", err)
+	}
+	return env, nil
+}
+
+func (c JSONCodec) Encode(item Item) ([]byte, error) {
+	env, err := c.Registry.Wrap(item)
+	if err != nil {
+		return nil, err
+	}
+	return c.EncodeEnvelope(env)
+}
+
+func (c JSONCodec) Decode(data []byte) (Item, error) {
+	env, err := c.DecodeEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	return c.Registry.Unwrap(env)
+}
+
+// GobCodec 以 encoding/gob 編碼信封, 同時實作 ItemCodec
+type GobCodec struct {
+	Registry *ItemRegistry
+}
+
+// EncodeEnvelope 將信封編碼為 gob
+func (c GobCodec) EncodeEnvelope(env Envelope) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(env); err != nil {
+		return nil, fmt.Errorf("item: encode envelope: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeEnvelope 由 gob 解碼信封
+func (c GobCodec) DecodeEnvelope(data []byte) (Envelope, error) {
+	var env Envelope
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&env); err != nil {
+		return Envelope{}, fmt.Errorf("item: decode envelope: %w", err)
+	}
+	return env, nil
+}
+
+func (c GobCodec) Encode(item Item) ([]byte, error) {
+	env, err := c.Registry.Wrap(item)
+	if err != nil {
+		return nil, err
+	}
+	return c.EncodeEnvelope(env)
+}
+
+func (c GobCodec) Decode(data []byte) (Item, error) {
+	env, err := c.DecodeEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	return c.Registry.Unwrap(env)
+}
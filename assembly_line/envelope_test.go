@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// priorityItem 帶有優先順序的測試物品
+type priorityItem struct {
+	testItem
+	priority int
+}
+
+func (i *priorityItem) Priority() int { return i.priority }
+
+// TestCodecs_RoundTrip 驗證內建物品經 JSON 及 gob 編碼後可還原
+func TestCodecs_RoundTrip(t *testing.T) {
+	registry := BuiltinItems()
+	codecs := map[string]ItemCodec{
+		"json": JSONCodec{Registry: registry},
+		"gob":  GobCodec{Registry: registry},
+	}
+	for name, codec := range codecs {
+		for _, item := range []Item{&Item1{ID: 1}, &Item2{ID: 2}, &Item3{ID: 3}} {
+			data, err := codec.Encode(item)
+			if err != nil {
+				t.Fatalf("%s Encode(%s) error = %v", name, item, err)
+			}
+			got, err := codec.Decode(data)
+			if err != nil {
+				t.Fatalf("%s Decode(%s) error = %v", name, item, err)
+			}
+			if got.String() != item.String() || itemType(got) != itemType(item) {
+				t.Errorf("%s round trip = %s (%s), want %s", name, got, itemType(got), item)
+			}
+		}
+	}
+}
+
+// TestEnvelope_Fields 驗證信封的欄位及 JSON 格式
+func TestEnvelope_Fields(t *testing.T) {
+	registry := BuiltinItems()
+	registry.Register(ItemType{
+		Name:   "urgent",
+		Encode: func(item Item) (int, json.RawMessage, error) { return item.(*priorityItem).id, nil, nil },
+		Decode: func(id int, _ json.RawMessage) (Item, error) { return &testItem{kind: "urgent", id: id}, nil },
+	})
+	env, err := registry.Wrap(&priorityItem{testItem: testItem{kind: "urgent", id: 7}, priority: 3})
+	if err != nil {
+		t.Fatalf("Wrap() error = %v", err)
+	}
+	if env.Type != "urgent" || env.ID != 7 || env.Priority != 3 {
+		t.Errorf("Wrap() = %+v, want urgent #7 priority 3", env)
+	}
+	// 類型名稱相同但不是內建物品時回傳錯誤
+	if _, err := registry.Wrap(&testItem{kind: "Item1"}); err == nil {
+		t.Error("Wrap(fake Item1) error = nil, want error")
+	}
+
+	env.Attempts = 2
+	codec := JSONCodec{Registry: registry}
+	data, _ := codec.EncodeEnvelope(env)
+	if want := `{"type":"urgent","id":7,"priority":3,"attempts":2}`; string(data) != want {
+		t.Errorf("EncodeEnvelope() = %s, want %s", data, want)
+	}
+	for name, c := range map[string]interface {
+		EncodeEnvelope(Envelope) ([]byte, error)
+		DecodeEnvelope([]byte) (Envelope, error)
+	}{"json": codec, "gob": GobCodec{Registry: registry}} {
+		data, err := c.EncodeEnvelope(env)
+		if err != nil {
+			t.Fatalf("%s EncodeEnvelope() error = %v", name, err)
+		}
+		got, err := c.DecodeEnvelope(data)
+		if err != nil {
+			t.Fatalf("%s DecodeEnvelope() error = %v", name, err)
+		}
+		if got.Type != env.Type || got.ID != env.ID || got.Priority != 3 || got.Attempts != 2 {
+			t.Errorf("%s envelope round trip = %+v, want %+v", name, got, env)
+		}
+	}
+}
+
+// TestRegistry_UnknownType 驗證未註冊的類型回傳清楚的錯誤
+func TestRegistry_UnknownType(t *testing.T) {
+	registry := BuiltinItems()
+	codec := JSONCodec{Registry: registry}
+
+	_, err := codec.Decode([]byte(`{"type":"Item9","id":1}`))
+	if !errors.Is(err, ErrUnknownItemType) {
+		t.Fatalf("Decode(Item9) error = %v, want ErrUnknownItemType", err)
+	}
+	if !strings.Contains(err.Error(), `"Item9"`) || !strings.Contains(err.Error(), "Item1, Item2, Item3") {
+		t.Errorf("error = %q, want type name and registered types", err)
+	}
+	if _, err := codec.Encode(&testItem{kind: "custom"}); !errors.Is(err, ErrUnknownItemType) {
+		t.Errorf("Encode(custom) error = %v, want ErrUnknownItemType", err)
+	}
+	if _, err := codec.Decode([]byte(`{"type":"Item1","id":1,"colour":"red"}`)); err == nil {
+		t.Error("Decode(unknown field) error = nil, want error")
+	}
+	if _, err := (GobCodec{Registry: registry}).Decode([]byte("garbage")); err == nil {
+		t.Error("gob Decode(garbage) error = nil, want error")
+	}
+}
+
+// TestRegistry_Register 驗證自訂類型的註冊及重複註冊
+func TestRegistry_Register(t *testing.T) {
+	registry := NewItemRegistry()
+	custom := ItemType{
+		Name: "custom",
+		Encode: func(item Item) (int, json.RawMessage, error) {
+			i := item.(*testItem)
+			payload, err := json.Marshal(i.d)
+			return i.id, payload, err
+		},
+		Decode: func(id int, payload json.RawMessage) (Item, error) {
+			i := &testItem{kind: "custom", id: id}
+			return i, json.Unmarshal(payload, &i.d)
+		},
+	}
+	if err := registry.Register(custom); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := registry.Register(custom); err == nil {
+		t.Error("Register(duplicate) error = nil, want error")
+	}
+	if err := registry.Register(ItemType{Name: "empty"}); err == nil {
+		t.Error("Register(without funcs) error = nil, want error")
+	}
+
+	codec := GobCodec{Registry: registry}
+	data, err := codec.Encode(&testItem{kind: "custom", id: 4, d: 42})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	got, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if i := got.(*testItem); i.id != 4 || i.d != 42 {
+		t.Errorf("round trip = %+v, want id 4, d 42", i)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Failure 員工的故障注入設定
+type Failure struct {
+	// Rate 每次開始處理時中途故障的機率
+	Rate float64
+	// AtAttempts 在第幾次開始處理時故障 (從 1 起算)
+	AtAttempts []int
+	// Recovery 故障後多久恢復, 0 表示故障後不再回來
+	Recovery time.Duration
+}
+
+// WithFailures 依序設定每位員工的故障注入, 多出的員工不會故障
+func WithFailures(failures ...Failure) Option {
+	return func(l *AssemblyLine) {
+		l.failures = failures
+	}
+}
+
+// shouldCrash 員工這次開始處理時是否故障
+func (l *AssemblyLine) shouldCrash(e *Employee) bool {
+	f := e.Failure
+	for _, n := range f.AtAttempts {
+		if n == e.attempts {
+			return true
+		}
+	}
+	if f.Rate <= 0 {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rng.Float64() < f.Rate
+}
+
+// crash 依故障設定決定員工是否在處理途中故障,
+// 故障時白做一半的預估時間, 並將手上的物品重新排入給其他員工
+func (l *AssemblyLine) crash(e *Employee, batch []*submission, speed float64) bool {
+	e.attempts++
+	if !l.shouldCrash(e) {
+		return false
+	}
+
+	var lost time.Duration
+	start := time.Now()
+	for _, s := range batch {
+		l.logStart(e, start, s.Item)
+		lost += l.durations.Estimate(s.Item)
+	}
+	lost = scaleDuration(lost, speed) / 2
+	time.Sleep(lost)
+
+	end := time.Now()
+	fmt.Fprintf(l.out, "[%s] 員工 #%d 處理 %s 時故障\n",
+		end.Format(timeLayout), e.ID, batch[0].String())
+	for _, s := range batch {
+		l.addSpan(e, s.Item, start, end, OutcomeCrashed)
+		if l.wal != nil {
+			l.wal.Failed(s.walID, fmt.Sprintf("員工 #%d 故障", e.ID))
+		}
+		l.onFailure(e, s.Item, ErrCrashed)
+		l.requeue(e, s)
+	}
+	e.crashes++
+	e.recordCrash()
+	e.lastType = ""
+
+	l.mu.Lock()
+	l.crashes++
+	l.lostTime += lost
+	l.mu.Unlock()
+	return true
+}
+
+// requeue 將員工手上的物品放回派發器, 仍是同一次提交
+func (l *AssemblyLine) requeue(e *Employee, s *submission) {
+	fmt.Fprintf(l.out, "[%s] 員工 #%d 的 %s 重新排入\n",
+		time.Now().Format(timeLayout), e.ID, s.String())
+	l.dispatcher.Push(s)
+
+	l.mu.Lock()
+	l.reassigned++
+	l.mu.Unlock()
+}
+
+// awaitRecovery 故障的員工等待恢復, 不會恢復時回傳 false
+func (l *AssemblyLine) awaitRecovery(e *Employee) bool {
+	d := e.Failure.Recovery
+	if d <= 0 {
+		fmt.Fprintf(l.out, "[%s] 員工 #%d 離線\n", time.Now().Format(timeLayout), e.ID)
+		e.setStatus(statusOffline, nil)
+		e.offAt = time.Since(l.startTime)
+		return false
+	}
+
+	e.setStatus(statusCrashed, nil)
+	time.Sleep(d)
+	fmt.Fprintf(l.out, "[%s] 員工 #%d 恢復 (耗時: %v)\n", time.Now().Format(timeLayout), e.ID, d)
+	e.unavailable += d
+	e.workingSince = time.Now()
+	e.setStatus(statusIdle, nil)
+
+	l.mu.Lock()
+	l.lostTime += d
+	l.mu.Unlock()
+	return true
+}
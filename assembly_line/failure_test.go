@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAssemblyLine_CrashRecovery 驗證故障時物品重新排入, 員工恢復後繼續處理
+func TestAssemblyLine_CrashRecovery(t *testing.T) {
+	f := Failure{AtAttempts: []int{2}, Recovery: 10 * time.Millisecond}
+	line := NewAssemblyLine(1, WithFailures(f), WithDurations(Durations{"A": 10 * time.Millisecond}))
+	stats := line.Run(newTestItems(4, 10*time.Millisecond, "A"))
+
+	if got := stats.TotalProcessed(); got != 4 {
+		t.Errorf("TotalProcessed = %d, want 4", got)
+	}
+	if stats.Crashes != 1 || stats.Reassigned != 1 {
+		t.Errorf("Crashes = %d, Reassigned = %d, want 1, 1", stats.Crashes, stats.Reassigned)
+	}
+	// 白做半件 (5ms) 加上恢復 10ms
+	if stats.LostTime != 15*time.Millisecond {
+		t.Errorf("LostTime = %v, want 15ms", stats.LostTime)
+	}
+	if stats.Employees[0].Crashes != 1 {
+		t.Errorf("employee crashes = %d, want 1", stats.Employees[0].Crashes)
+	}
+	if stats.Employees[0].Unavailable != 10*time.Millisecond {
+		t.Errorf("Unavailable = %v, want 10ms", stats.Employees[0].Unavailable)
+	}
+}
+
+// TestAssemblyLine_CrashReassign 驗證故障離線員工的物品由其他員工接手
+func TestAssemblyLine_CrashReassign(t *testing.T) {
+	line := NewAssemblyLine(2, WithFailures(Failure{AtAttempts: []int{1}}))
+	stats := line.Run(newTestItems(6, 5*time.Millisecond, "A"))
+
+	if got := stats.TotalProcessed(); got != 6 {
+		t.Errorf("TotalProcessed = %d, want 6", got)
+	}
+	if stats.Employees[0].Processed != 0 {
+		t.Errorf("crashed employee processed %d, want 0", stats.Employees[0].Processed)
+	}
+	if stats.Employees[1].Processed != 6 {
+		t.Errorf("employee 2 processed %d, want 6", stats.Employees[1].Processed)
+	}
+}
+
+// TestAssemblyLine_AllCrashed 驗證所有員工離線時 Run 仍會結束並回報未處理物品
+func TestAssemblyLine_AllCrashed(t *testing.T) {
+	line := NewAssemblyLine(2, WithSeed(1), WithFailures(Failure{Rate: 1}, Failure{Rate: 1}))
+	stats := line.Run(newTestItems(3, time.Millisecond, "A"))
+
+	if got := stats.TotalProcessed(); got != 0 {
+		t.Errorf("TotalProcessed = %d, want 0", got)
+	}
+	if stats.Crashes != 2 {
+		t.Errorf("Crashes = %d, want 2", stats.Crashes)
+	}
+	if stats.Submitted != 3 {
+		t.Errorf("Submitted = %d, want 3", stats.Submitted)
+	}
+}
+
+// TestAssemblyLine_CrashBatch 驗證批次處理中故障時整批重新排入
+func TestAssemblyLine_CrashBatch(t *testing.T) {
+	line := NewAssemblyLine(1,
+		WithBatch(3, 10*time.Millisecond),
+		WithFailures(Failure{AtAttempts: []int{1}, Recovery: time.Millisecond}))
+	stats := line.Run(newTestItems(3, time.Millisecond, "A"))
+
+	if stats.Reassigned != 3 {
+		t.Errorf("Reassigned = %d, want 3", stats.Reassigned)
+	}
+	if got := stats.TotalProcessed(); got != 3 {
+		t.Errorf("TotalProcessed = %d, want 3", got)
+	}
+}
+
+// TestAssemblyLine_RequeueFullQueue 驗證佇列已滿時故障的員工仍可重新排入物品, Stop 不會卡住
+func TestAssemblyLine_RequeueFullQueue(t *testing.T) {
+	line := NewAssemblyLine(1, WithQueueCapacity(1), WithFailures(Failure{AtAttempts: []int{1}}))
+	line.Start()
+	for _, item := range newTestItems(2, 10*time.Millisecond, "A") {
+		if err := line.Submit(item); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+
+	done := make(chan Stats)
+	go func() { done <- line.Stop() }()
+	select {
+	case stats := <-done:
+		if stats.Reassigned != 1 || stats.Unfinished != 2 {
+			t.Errorf("Reassigned = %d, Unfinished = %d, want 1 and 2", stats.Reassigned, stats.Unfinished)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop() did not return")
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Tenanted 可選介面, 回傳物品所屬的租戶, 公平分配時以租戶取代類型分組
+type Tenanted interface {
+	Tenant() string
+}
+
+// itemClass 公平分配時物品所屬的分組, 有租戶時為租戶, 否則為類型
+func itemClass(item Item) string {
+	if t, ok := item.(Tenanted); ok && t.Tenant() != "" {
+		return t.Tenant()
+	}
+	return itemType(item)
+}
+
+// Shares 各分組可分得員工時間的權重, 未設定的分組為 1
+type Shares map[string]float64
+
+// Weight 回傳分組的權重
+func (s Shares) Weight(class string) float64 {
+	if w, ok := s[class]; ok && w > 0 {
+		return w
+	}
+	return 1
+}
+
+// fairItem 排隊中的物品及其虛擬完成時間
+type fairItem struct {
+	item   Item
+	finish float64
+}
+
+// fairDispatcher 加權公平佇列 (self-clocked fair queueing):
+// 每件物品依預估處理時間除以分組權重取得虛擬完成時間, 每次派發最小者,
+// 讓各分組依權重分得員工時間, 大量湧入的分組不會餓死其他分組
+type fairDispatcher struct {
+	shares Shares
+
+	mu     sync.Mutex
+	queues map[string][]fairItem
+	// last 各分組最後一件物品的虛擬完成時間, vtime 為最近派發物品的虛擬完成時間
+	last    map[string]float64
+	vtime   float64
+	size    int
+	closed  bool
+	changed chan struct{}
+}
+
+// NewFairDispatcher 創建依 shares 權重公平分配的派發器,
+// 未實作 Estimator 的物品每件以 1ms 計算
+func NewFairDispatcher(shares Shares) DispatcherFactory {
+	return func(employees []*Employee) Dispatcher {
+		return &fairDispatcher{
+			shares:  shares,
+			queues:  make(map[string][]fairItem),
+			last:    make(map[string]float64),
+			changed: make(chan struct{}),
+		}
+	}
+}
+
+func (d *fairDispatcher) Push(item Item) {
+	class := itemClass(item)
+	cost := estimate(item)
+	if cost <= 0 {
+		cost = time.Millisecond
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	start := d.last[class]
+	if start < d.vtime {
+		start = d.vtime
+	}
+	finish := start + float64(cost)/d.shares.Weight(class)
+	d.last[class] = finish
+	d.queues[class] = append(d.queues[class], fairItem{item: item, finish: finish})
+	d.size++
+	d.broadcast()
+}
+
+func (d *fairDispatcher) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	d.broadcast()
+}
+
+// broadcast 喚醒所有等待中的員工, 呼叫時需持有鎖
+func (d *fairDispatcher) broadcast() {
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+func (d *fairDispatcher) Next(ctx context.Context, e *Employee) (Item, error) {
+	for {
+		d.mu.Lock()
+		if item, ok := d.pop(); ok {
+			d.mu.Unlock()
+			return item, nil
+		}
+		if d.closed {
+			d.mu.Unlock()
+			return nil, ErrDrained
+		}
+		changed := d.changed
+		d.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// pop 取出虛擬完成時間最小的物品, 相同時依分組名稱, 呼叫時需持有鎖
+func (d *fairDispatcher) pop() (Item, bool) {
+	best := ""
+	for class, q := range d.queues {
+		if len(q) == 0 {
+			continue
+		}
+		if best == "" || q[0].finish < d.queues[best][0].finish ||
+			(q[0].finish == d.queues[best][0].finish && class < best) {
+			best = class
+		}
+	}
+	if best == "" {
+		return nil, false
+	}
+
+	q := d.queues[best]
+	head := q[0]
+	q[0] = fairItem{}
+	d.queues[best] = q[1:]
+	d.vtime = head.finish
+	d.size--
+	return head.item, true
+}
+
+func (d *fairDispatcher) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.size
+}
+
+// WithFairShare 以加權公平佇列派發, 並統計各分組的吞吐量
+func WithFairShare(shares Shares) Option {
+	return func(l *AssemblyLine) {
+		l.shares = shares
+		l.newDispatcher = NewFairDispatcher(shares)
+	}
+}
+
+// ClassStats 公平分配時單一分組的統計
+type ClassStats struct {
+	Weight    float64
+	Processed int
+	Busy      time.Duration
+}
+
+// Throughput 每秒處理的物品數
+func (c ClassStats) Throughput(total time.Duration) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(c.Processed) / total.Seconds()
+}
+
+// recordClass 累計各分組的處理數量及時間, 同批物品平分處理時間
+func (l *AssemblyLine) recordClass(items []Item, busy time.Duration) {
+	if l.shares == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, item := range items {
+		class := itemClass(item)
+		cs, ok := l.classes[class]
+		if !ok {
+			cs = &ClassStats{Weight: l.shares.Weight(class)}
+			l.classes[class] = cs
+		}
+		cs.Processed++
+		cs.Busy += busy / time.Duration(len(items))
+	}
+}
+
+// classNames 依名稱排序的分組
+func (s Stats) classNames() []string {
+	names := make([]string, 0, len(s.Classes))
+	for class := range s.Classes {
+		names = append(names, class)
+	}
+	sort.Strings(names)
+	return names
+}
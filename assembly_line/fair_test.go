@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tenantItem 屬於某租戶的測試物品
+type tenantItem struct {
+	testItem
+	tenant string
+}
+
+func (i *tenantItem) Tenant() string {
+	return i.tenant
+}
+
+// TestItemClass 驗證有租戶時以租戶分組, 否則以類型分組
+func TestItemClass(t *testing.T) {
+	if got := itemClass(&testItem{kind: "A"}); got != "A" {
+		t.Errorf("itemClass(testItem) = %q, want A", got)
+	}
+	if got := itemClass(&tenantItem{testItem: testItem{kind: "A"}, tenant: "acme"}); got != "acme" {
+		t.Errorf("itemClass(tenantItem) = %q, want acme", got)
+	}
+	if got := itemClass(&tenantItem{testItem: testItem{kind: "A"}}); got != "A" {
+		t.Errorf("itemClass(tenantItem without tenant) = %q, want A", got)
+	}
+}
+
+// TestFairDispatcher_NoStarvation 驗證大量湧入的類型不會讓其他類型等到最後
+func TestFairDispatcher_NoStarvation(t *testing.T) {
+	d := NewFairDispatcher(nil)(nil)
+	for _, item := range newTestItems(10, 200*time.Millisecond, "Item3") {
+		d.Push(item)
+	}
+	for _, item := range newTestItems(2, 100*time.Millisecond, "Item1") {
+		d.Push(item)
+	}
+	d.Close()
+
+	got := drain(t, d, &Employee{ID: 1})
+	if len(got) != 12 {
+		t.Fatalf("got %d items, want 12", len(got))
+	}
+	if itemType(got[0]) != "Item1" || itemType(got[1]) != "Item1" {
+		t.Errorf("order = %v, want the two Item1 first", got)
+	}
+}
+
+// TestFairDispatcher_Weights 驗證各分組依權重分得處理時間
+func TestFairDispatcher_Weights(t *testing.T) {
+	d := NewFairDispatcher(Shares{"X": 3})(nil)
+	for _, item := range newTestItems(40, 100*time.Millisecond, "X", "Y") {
+		d.Push(item)
+	}
+	d.Close()
+
+	counts := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		item, err := d.Next(context.Background(), &Employee{ID: 1})
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		counts[itemType(item)]++
+	}
+	if counts["X"] != 15 || counts["Y"] != 5 {
+		t.Errorf("first 20 items = %v, want X:15 Y:5", counts)
+	}
+	if got := d.(lener).Len(); got != 60 {
+		t.Errorf("Len() = %d, want 60", got)
+	}
+}
+
+// TestAssemblyLine_FairShare 驗證公平分配的分組統計及輸出
+func TestAssemblyLine_FairShare(t *testing.T) {
+	var items []Item
+	for i := 0; i < 6; i++ {
+		items = append(items,
+			&tenantItem{testItem: testItem{kind: "A", id: i, d: time.Millisecond}, tenant: "big"},
+			&tenantItem{testItem: testItem{kind: "A", id: i, d: time.Millisecond}, tenant: "big"},
+			&tenantItem{testItem: testItem{kind: "B", id: i, d: time.Millisecond}, tenant: "small"})
+	}
+	line := NewAssemblyLine(2, WithFairShare(Shares{"big": 2}))
+	stats := line.Run(items)
+
+	if got := stats.TotalProcessed(); got != 18 {
+		t.Errorf("TotalProcessed = %d, want 18", got)
+	}
+	big, small := stats.Classes["big"], stats.Classes["small"]
+	if big.Processed != 12 || small.Processed != 6 {
+		t.Errorf("Classes = %+v, want big 12, small 6", stats.Classes)
+	}
+	if big.Weight != 2 || small.Weight != 1 {
+		t.Errorf("weights = %v, %v, want 2, 1", big.Weight, small.Weight)
+	}
+	if big.Throughput(stats.TotalTime) <= 0 {
+		t.Errorf("Throughput = %v, want > 0", big.Throughput(stats.TotalTime))
+	}
+
+	var buf bytes.Buffer
+	stats.Print(&buf)
+	if !strings.Contains(buf.String(), "big (權重 2): 處理 12 件") {
+		t.Errorf("output does not contain class stats:\n%s", buf.String())
+	}
+	if r := NewReport(stats, 1, nil); len(r.Classes) != 2 || r.Classes[0].Class != "big" {
+		t.Errorf("report Classes = %+v", r.Classes)
+	}
+}
+
+// TestAssemblyLine_DispatcherReplacesFairShare 驗證之後設定的派發策略取代公平分配, 不再輸出分組統計
+func TestAssemblyLine_DispatcherReplacesFairShare(t *testing.T) {
+	items := []Item{&tenantItem{testItem: testItem{kind: "A", id: 1}, tenant: "big"}}
+	line := NewAssemblyLine(1, WithFairShare(Shares{"big": 2}), WithDispatcher(NewAffinityDispatcher))
+	stats := line.Run(items)
+
+	if stats.TotalProcessed() != 1 || len(stats.Classes) != 0 {
+		t.Errorf("TotalProcessed() = %d, Classes = %+v, want 1 and none", stats.TotalProcessed(), stats.Classes)
+	}
+}
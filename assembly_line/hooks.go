@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrVetoed 物品被 BeforeStart 掛勾否決, 不會處理
+var ErrVetoed = errors.New("assembly line: item vetoed")
+
+// ErrCrashed 員工處理途中故障, 物品會重新排入
+var ErrCrashed = errors.New("assembly line: employee crashed")
+
+// Hooks 嵌入流水線時在每件物品前後執行的掛勾, 未設定的欄位略過
+type Hooks struct {
+	// BeforeStart 員工開始處理前呼叫, 回傳的物品取代原本的物品處理;
+	// 回傳錯誤時否決該物品, 不再處理, 結果的錯誤包含 ErrVetoed
+	BeforeStart func(e *Employee, item Item) (Item, error)
+	// AfterFinish 物品處理完成後呼叫, err 為物品回傳的錯誤
+	AfterFinish func(e *Employee, item Item, value any, err error)
+	// OnFailure 物品回傳錯誤, 或員工處理途中故障 (err 為 ErrCrashed) 時呼叫
+	OnFailure func(e *Employee, item Item, err error)
+	// OnIdle 員工處理完一批物品、回到閒置時呼叫
+	OnIdle func(e *Employee)
+}
+
+// ProcessFunc 處理一件物品, 回傳其輸出
+type ProcessFunc func(e *Employee, item Item) (any, error)
+
+// Middleware 包裝物品的處理, 可在呼叫 next 前後加入行為、改用其他物品或不呼叫 next
+type Middleware func(next ProcessFunc) ProcessFunc
+
+// WithHooks 註冊掛勾, 可多次使用, 依註冊順序執行
+func WithHooks(h Hooks) Option {
+	return func(l *AssemblyLine) {
+		l.hooks = append(l.hooks, h)
+	}
+}
+
+// WithMiddleware 註冊處理物品的中介層, 先註冊的在最外層;
+// 有中介層時可批次處理的物品也改為逐件處理
+func WithMiddleware(m ...Middleware) Option {
+	return func(l *AssemblyLine) {
+		l.middleware = append(l.middleware, m...)
+	}
+}
+
+// hooked 是否需要逐件處理以執行掛勾或中介層
+func (l *AssemblyLine) hooked() bool {
+	return len(l.hooks) > 0 || len(l.middleware) > 0
+}
+
+// processFunc 以速度 speed 處理物品, 外面依序包上中介層
+func (l *AssemblyLine) processFunc(speed float64) ProcessFunc {
+	fn := ProcessFunc(func(e *Employee, item Item) (any, error) {
+		return processWithSpeed(item, speed)
+	})
+	for i := len(l.middleware) - 1; i >= 0; i-- {
+		fn = l.middleware[i](fn)
+	}
+	return fn
+}
+
+// beforeStart 依序執行 BeforeStart, 回傳最後要處理的物品; 任一個否決時回傳錯誤
+func (l *AssemblyLine) beforeStart(e *Employee, item Item) (Item, error) {
+	for _, h := range l.hooks {
+		if h.BeforeStart == nil {
+			continue
+		}
+		next, err := h.BeforeStart(e, item)
+		if err != nil {
+			if !errors.Is(err, ErrVetoed) {
+				err = fmt.Errorf("%w: %w", ErrVetoed, err)
+			}
+			return nil, err
+		}
+		if next != nil {
+			item = next
+		}
+	}
+	return item, nil
+}
+
+func (l *AssemblyLine) afterFinish(e *Employee, item Item, value any, err error) {
+	for _, h := range l.hooks {
+		if h.AfterFinish != nil {
+			h.AfterFinish(e, item, value, err)
+		}
+	}
+	if err != nil {
+		l.onFailure(e, item, err)
+	}
+}
+
+func (l *AssemblyLine) onFailure(e *Employee, item Item, err error) {
+	for _, h := range l.hooks {
+		if h.OnFailure != nil {
+			h.OnFailure(e, item, err)
+		}
+	}
+}
+
+func (l *AssemblyLine) onIdle(e *Employee) {
+	for _, h := range l.hooks {
+		if h.OnIdle != nil {
+			h.OnIdle(e)
+		}
+	}
+}
+
+// veto 物品被否決, 不處理但計為結束, 以便流水線可以停止
+func (l *AssemblyLine) veto(e *Employee, s *submission, err error) {
+	fmt.Fprintf(l.out, "[%s] 員工 #%d 的 %s 被否決: %v\n",
+		time.Now().Format(timeLayout), e.ID, s.String(), err)
+	if !l.credit(s) {
+		l.rejectCompletion(e, s.Item)
+		return
+	}
+	l.mu.Lock()
+	l.vetoed++
+	l.mu.Unlock()
+	l.finishItem(e, s, nil, err, time.Now())
+}
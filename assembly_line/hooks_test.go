@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failingItem 處理時回傳錯誤的測試物品
+type failingItem struct{ testItem }
+
+func (i *failingItem) ProcessResult() (any, error) {
+	return nil, fmt.Errorf("%s failed", i.String())
+}
+
+// eventLog 記錄掛勾執行的順序
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(event string) {
+	l.mu.Lock()
+	l.events = append(l.events, event)
+	l.mu.Unlock()
+}
+
+// TestHooks_Order 驗證掛勾依註冊順序執行, 先註冊的中介層在最外層
+func TestHooks_Order(t *testing.T) {
+	var log eventLog
+	hooks := func(name string) Hooks {
+		return Hooks{
+			BeforeStart: func(e *Employee, item Item) (Item, error) {
+				log.add(name + " before " + item.String())
+				return item, nil
+			},
+			AfterFinish: func(e *Employee, item Item, value any, err error) { log.add(name + " after") },
+			OnIdle:      func(e *Employee) { log.add(name + " idle") },
+		}
+	}
+	middleware := func(name string) Middleware {
+		return func(next ProcessFunc) ProcessFunc {
+			return func(e *Employee, item Item) (any, error) {
+				log.add(name + " in")
+				defer log.add(name + " out")
+				return next(e, item)
+			}
+		}
+	}
+
+	line := NewAssemblyLine(1, WithHooks(hooks("h1")), WithHooks(hooks("h2")),
+		WithMiddleware(middleware("m1"), middleware("m2")))
+	line.Run(newTestItems(1, 0, "a"))
+
+	want := []string{"h1 before a #1", "h2 before a #1", "m1 in", "m2 in", "m2 out", "m1 out",
+		"h1 after", "h2 after", "h1 idle", "h2 idle"}
+	if !reflect.DeepEqual(log.events, want) {
+		t.Errorf("events = %v, want %v", log.events, want)
+	}
+}
+
+// TestHooks_VetoAndReplace 驗證 BeforeStart 可否決或替換物品, 且完成數仍可核對
+func TestHooks_VetoAndReplace(t *testing.T) {
+	var original, replaced int32
+	items := newTestItems(4, time.Millisecond, "a")
+	for _, item := range items {
+		item.(*testItem).processed = &original
+	}
+	hooks := Hooks{
+		BeforeStart: func(e *Employee, item Item) (Item, error) {
+			switch item.(*testItem).id {
+			case 2:
+				return nil, errors.New("not allowed")
+			case 3:
+				return &testItem{kind: "a", id: 30, processed: &replaced}, nil
+			}
+			return item, nil
+		},
+	}
+	stats := NewAssemblyLine(2, WithResults(SubmissionOrder), WithHooks(hooks)).Run(items)
+
+	if original != 2 || replaced != 1 {
+		t.Errorf("processed original = %d, replaced = %d, want 2 and 1", original, replaced)
+	}
+	if stats.Vetoed != 1 || stats.TotalProcessed() != 3 {
+		t.Errorf("Vetoed = %d, TotalProcessed() = %d, want 1 and 3", stats.Vetoed, stats.TotalProcessed())
+	}
+	if err := stats.Results[1].Err; !errors.Is(err, ErrVetoed) {
+		t.Errorf("vetoed result error = %v, want ErrVetoed", err)
+	}
+	// 結果仍對應提交的物品
+	if stats.Results[2].Item != items[2] {
+		t.Errorf("replaced result item = %v, want %v", stats.Results[2].Item, items[2])
+	}
+	if err := stats.Reconcile(); err != nil {
+		t.Errorf("Reconcile() = %v, want nil", err)
+	}
+}
+
+// TestHooks_OnFailure 驗證物品錯誤及員工故障都會通知 OnFailure
+func TestHooks_OnFailure(t *testing.T) {
+	var crashed, failed int32
+	hooks := Hooks{
+		OnFailure: func(e *Employee, item Item, err error) {
+			if errors.Is(err, ErrCrashed) {
+				atomic.AddInt32(&crashed, 1)
+			} else {
+				atomic.AddInt32(&failed, 1)
+			}
+		},
+	}
+	items := []Item{&failingItem{testItem{kind: "a", id: 1}}, &testItem{kind: "a", id: 2}}
+	line := NewAssemblyLine(1, WithHooks(hooks),
+		WithFailures(Failure{AtAttempts: []int{1}, Recovery: time.Millisecond}))
+	stats := line.Run(items)
+
+	if crashed != 1 || failed != 1 {
+		t.Errorf("crashed = %d, failed = %d, want 1 each", crashed, failed)
+	}
+	if stats.TotalProcessed() != 2 {
+		t.Errorf("TotalProcessed() = %d, want 2", stats.TotalProcessed())
+	}
+}
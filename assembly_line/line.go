@@ -0,0 +1,1066 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const timeLayout = "2006-01-02 15:04:05.000"
+
+// Typed 可選介面, 讓物品自訂類型名稱
+type Typed interface {
+	Type() string
+}
+
+// itemType 取得物品類型名稱, 預設為結構名稱 (例如 Item1)
+func itemType(item Item) string {
+	if t, ok := item.(Typed); ok {
+		return t.Type()
+	}
+	rt := reflect.TypeOf(item)
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	return rt.Name()
+}
+
+// submission 一次提交, 派發器中傳遞的是提交而非物品本身,
+// 同一件物品提交多次或物品不可比較 (例如含有 slice 的結構) 時仍能分開記錄
+type submission struct {
+	Item
+	// seq 在這次執行中的提交順序 (從 0 起算)
+	seq int
+	// due 延遲物品的放行時間, 第一次開始處理後清除; credited 是否已計入完成或否決,
+	// 兩者都受 AssemblyLine.mu 保護
+	due      time.Time
+	credited bool
+	// slot 是否佔用佇列名額, 員工第一次取走時歸還
+	slot bool
+	// walID 啟用預寫日誌時這次提交的紀錄編號
+	walID uint64
+}
+
+// Type、Tenant 及 EstimatedDuration 轉交給原本的物品, 讓派發器照常分類及排序
+
+func (s *submission) Type() string { return itemType(s.Item) }
+
+func (s *submission) Tenant() string {
+	if t, ok := s.Item.(Tenanted); ok {
+		return t.Tenant()
+	}
+	return ""
+}
+
+func (s *submission) EstimatedDuration() time.Duration { return estimate(s.Item) }
+
+// AssemblyLine 流水線, 由多位員工透過派發器取物品處理
+type AssemblyLine struct {
+	employees     []*Employee
+	out           io.Writer
+	newDispatcher DispatcherFactory
+	changeover    Changeover
+	skills        []Skill
+	durations     Durations
+	schedules     []Schedule
+	failures      []Failure
+	limiter       *TokenBucket
+	typeLimiters  map[string]*TokenBucket
+	shares        Shares
+	wal           *WAL
+	hooks         []Hooks
+	middleware    []Middleware
+	dedup         *dedupWindow
+	resultOrder   *ResultOrder
+	resultSink    chan<- Result
+	sinkClosed    bool
+	reorderWindow int
+	history       int
+	dashboard     *dashboard
+	rng           *rand.Rand
+	startTime     time.Time
+
+	// dispatcher 本次執行的派發器, pending 為尚未完成的物品數,
+	// 歸零時關閉派發器; 故障時物品會重新放回派發器
+	dispatcher Dispatcher
+	pending    int64
+	// held 為 pending 中保留到停止的那一件, 停止後歸零
+	held int64
+	// submitted 為已提交的件數; arrivals 為已提交的物品, 只在保留全部歷史時記錄,
+	// 供估計先進先出的切換時間
+	submitted int
+	arrivals  []Item
+	// keep 本次執行保留的時間軸及結果筆數, 小於 0 時全部保留
+	keep      int
+	collector *resultCollector
+	// delays 尚未到排程時間的物品
+	delays     *delayQueue
+	stopDelays chan struct{}
+	// wg 等待所有員工結束
+	wg sync.WaitGroup
+	// accepting 是否接受 Submit, 受 submitMu 保護; 停止時取得寫鎖以免與 Submit 同時進行
+	submitMu  sync.RWMutex
+	accepting bool
+	// stopMu 讓同時呼叫的 Stop 依序進行; last 為上次停止時的統計
+	stopMu sync.Mutex
+	last   Stats
+	// slots 佇列中等待員工取走的提交名額, 容量為 queueCapacity;
+	// 重新排入的物品不佔名額, 因此不會因佇列已滿而卡住
+	slots chan struct{}
+	// working 仍在工作 (尚未下班或離線) 的員工數
+	working int64
+
+	batchSize     int
+	batchMaxWait  time.Duration
+	queueCapacity int
+
+	mu            sync.Mutex
+	batchSizes    map[int]int
+	types         map[string]*TypeStats
+	classes       map[string]*ClassStats
+	changeovers   int
+	changeoverDur time.Duration
+	crashes       int
+	reassigned    int
+	lostTime      time.Duration
+	timeline      []Span
+	delayed       int
+	late          int
+	lateness      time.Duration
+	maxLateness   time.Duration
+	duplicates    int
+	rejected      int
+	vetoed        int
+}
+
+// ErrStopped 流水線未啟動或已停止, 不接受新物品
+var ErrStopped = errors.New("assembly line: stopped")
+
+// ErrQueueFull 佇列已滿, TrySubmit 不等待
+var ErrQueueFull = errors.New("assembly line: queue full")
+
+// defaultQueueCapacity 長時間運行時先進先出佇列的預設容量
+const defaultQueueCapacity = 1024
+
+// Option 流水線設定
+type Option func(*AssemblyLine)
+
+// WithOutput 設定處理紀錄的輸出位置
+func WithOutput(w io.Writer) Option {
+	return func(l *AssemblyLine) {
+		l.out = w
+	}
+}
+
+// WithBatch 啟用批次模式, 員工一次最多取 size 件同類物品,
+// 湊不滿時最多等待 maxWait 就開始處理
+func WithBatch(size int, maxWait time.Duration) Option {
+	return func(l *AssemblyLine) {
+		l.batchSize = size
+		l.batchMaxWait = maxWait
+	}
+}
+
+// WithDispatcher 設定派發策略, 預設為共用 channel 的先進先出;
+// 與 WithFairShare 一樣以最後設定的為準, 取代公平分配時不再統計各分組
+func WithDispatcher(f DispatcherFactory) Option {
+	return func(l *AssemblyLine) {
+		l.newDispatcher = f
+		l.shares = nil
+	}
+}
+
+// WithChangeover 設定所有員工切換物品類型的準備時間,
+// 個別員工可再透過 Employees() 修改
+func WithChangeover(c Changeover) Option {
+	return func(l *AssemblyLine) {
+		l.changeover = c
+	}
+}
+
+// WithSkills 依序設定每位員工的速度倍率, 多出的員工維持預設速度
+func WithSkills(skills ...Skill) Option {
+	return func(l *AssemblyLine) {
+		l.skills = skills
+	}
+}
+
+// WithDurations 設定各類型物品的處理時間, 用來估計員工故障時白做的時間及模擬派發策略
+func WithDurations(d Durations) Option {
+	return func(l *AssemblyLine) {
+		l.durations = d
+	}
+}
+
+// WithSeed 設定隨機來源的種子, 用於故障注入等隨機行為
+func WithSeed(seed int64) Option {
+	return func(l *AssemblyLine) {
+		l.rng = rand.New(rand.NewSource(seed))
+	}
+}
+
+// WithQueueCapacity 設定以 Start 長時間運行時佇列中等待員工取走的物品上限,
+// 超過時 Submit 等待, TrySubmit 回傳 ErrQueueFull
+func WithQueueCapacity(n int) Option {
+	return func(l *AssemblyLine) {
+		l.queueCapacity = n
+	}
+}
+
+// NewAssemblyLine 創建有 numEmployees 位員工的流水線
+func NewAssemblyLine(numEmployees int, opts ...Option) *AssemblyLine {
+	l := &AssemblyLine{
+		out:           io.Discard,
+		batchSize:     1,
+		queueCapacity: defaultQueueCapacity,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.dashboard != nil {
+		l.out = io.Discard
+	}
+	if l.batchSize < 1 {
+		l.batchSize = 1
+	}
+	if l.rng == nil {
+		l.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	l.employees = make([]*Employee, numEmployees)
+	for i := range l.employees {
+		l.employees[i] = &Employee{ID: i + 1, Changeover: l.changeover}
+		if i < len(l.skills) {
+			l.employees[i].Speed = l.skills[i]
+		}
+		if i < len(l.schedules) {
+			l.employees[i].Schedule = l.schedules[i]
+		}
+		if i < len(l.failures) {
+			l.employees[i].Failure = l.failures[i]
+		}
+	}
+	return l
+}
+
+// Employees 回傳流水線上的員工
+func (l *AssemblyLine) Employees() []*Employee {
+	return l.employees
+}
+
+// Run 依序派發物品給員工, 全部處理完後回傳統計
+func (l *AssemblyLine) Run(items []Item) Stats {
+	// 先放入所有物品再啟動員工, 全部完成後才關閉派發器, 以便故障時重新排入
+	l.open(len(items), -1)
+	for _, item := range items {
+		_, err := l.submit(item, idempotencyKey(item), true)
+		if errors.Is(err, ErrDuplicate) {
+			fmt.Fprintf(l.out, "[%s] %s 重複提交, 略過\n", time.Now().Format(timeLayout), item.String())
+		} else if err != nil {
+			fmt.Fprintf(l.out, "[%s] %s 無法提交: %v\n", time.Now().Format(timeLayout), item.String(), err)
+		}
+	}
+	l.startWorkers()
+	return l.finish()
+}
+
+// Start 啟動長時間運行的流水線, 之後以 Submit 持續加入物品, 以 Stop 結束;
+// 時間軸及結果只依 WithHistory 保留最近的部分
+func (l *AssemblyLine) Start() {
+	l.open(l.queueCapacity, l.history)
+	l.startWorkers()
+}
+
+// Submit 在 Start 之後加入一件物品, 佇列已滿時等待;
+// 流水線未啟動或已停止時回傳 ErrStopped, 無法寫入預寫日誌時回傳其錯誤,
+// 啟用 WithDedup 且冪等鍵重複時回傳 ErrDuplicate
+func (l *AssemblyLine) Submit(item Item) error {
+	_, err := l.submitKey(item, idempotencyKey(item), true)
+	return err
+}
+
+// TrySubmit 與 Submit 相同, 但佇列中已有 WithQueueCapacity 件物品等待時
+// 不等待, 直接回傳 ErrQueueFull
+func (l *AssemblyLine) TrySubmit(item Item) error {
+	_, err := l.submitKey(item, idempotencyKey(item), false)
+	return err
+}
+
+// submitKey 以指定的冪等鍵提交, 供物品本身不帶鍵 (例如由信封還原) 時使用,
+// wait 為 false 時佇列已滿不等待; 回傳提交順序 (即結果的 Seq)
+func (l *AssemblyLine) submitKey(item Item, key string, wait bool) (int, error) {
+	l.submitMu.RLock()
+	defer l.submitMu.RUnlock()
+	if !l.accepting {
+		return 0, ErrStopped
+	}
+	return l.submit(item, key, wait)
+}
+
+// Stop 停止接受新物品, 等待已提交的物品全部完成後回傳統計;
+// 已停止時回傳上次的統計, 從未啟動時回傳零值
+func (l *AssemblyLine) Stop() Stats {
+	return l.finish()
+}
+
+// open 準備一次執行, keep 為保留的時間軸及結果筆數
+func (l *AssemblyLine) open(capacity, keep int) {
+	l.startTime = time.Now()
+	l.keep = keep
+	l.reset()
+
+	if l.newDispatcher != nil {
+		l.dispatcher = l.newDispatcher(l.employees)
+	} else {
+		l.dispatcher = newQueueDispatcher(func([]Item, *Employee) int { return 0 })
+	}
+	l.slots = make(chan struct{}, max(capacity, 1))
+	// 保留一件直到停止, 避免物品暫時處理完時就關閉派發器
+	l.pending = 1
+	l.held = 1
+	l.submitted = 0
+	l.arrivals = nil
+	l.stopDelays = make(chan struct{})
+	l.delays = newDelayQueue(func(item Item) {
+		l.release(item.(*submission))
+	})
+	go l.delays.run(l.stopDelays)
+
+	if l.dashboard != nil {
+		l.dashboard.start(l)
+	}
+	l.submitMu.Lock()
+	l.accepting = true
+	l.submitMu.Unlock()
+}
+
+// submit 記錄提交順序後放入派發器, 尚未到排程時間的放入延遲佇列, 回傳提交順序;
+// 先取得佇列名額 (wait 為 false 且已滿時回傳 ErrQueueFull), 再以冪等鍵去重,
+// 啟用預寫日誌時再寫入日誌, 失敗則不提交
+func (l *AssemblyLine) submit(item Item, key string, wait bool) (int, error) {
+	if wait {
+		l.slots <- struct{}{}
+	} else {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			return 0, ErrQueueFull
+		}
+	}
+	if err := l.claimKey(key); err != nil {
+		<-l.slots
+		return 0, err
+	}
+	var walID uint64
+	if l.wal != nil {
+		id, err := l.wal.Submit(item)
+		if err != nil {
+			l.releaseKey(key)
+			<-l.slots
+			return 0, err
+		}
+		walID = id
+	}
+	item = unkeyed(item)
+
+	l.mu.Lock()
+	s := &submission{Item: item, seq: l.submitted, walID: walID}
+	l.submitted++
+	if l.keep < 0 {
+		l.arrivals = append(l.arrivals, item)
+	}
+	if l.dashboard != nil {
+		l.dashboard.totals[itemType(item)]++
+	}
+	l.mu.Unlock()
+
+	atomic.AddInt64(&l.pending, 1)
+	if at, ok := releaseAt(item, time.Now()); ok && at.After(time.Now()) {
+		// 延遲佇列中的物品不佔名額
+		<-l.slots
+		l.deferItem(s, at)
+		return s.seq, nil
+	}
+	s.slot = true
+	l.dispatcher.Push(s)
+	return s.seq, nil
+}
+
+// take 員工取走提交時歸還其佔用的佇列名額
+func (l *AssemblyLine) take(s *submission) *submission {
+	if s.slot {
+		s.slot = false
+		<-l.slots
+	}
+	return s
+}
+
+// startWorkers 啟動員工 goroutines
+func (l *AssemblyLine) startWorkers() {
+	l.working = int64(len(l.employees))
+	for _, emp := range l.employees {
+		l.wg.Add(1)
+		go func(e *Employee) {
+			defer l.wg.Done()
+			l.work(e)
+			atomic.AddInt64(&l.working, -1)
+			if l.collector != nil {
+				l.collector.wake()
+			}
+		}(emp)
+	}
+}
+
+// finish 停止接受新物品, 等待員工處理完畢後整理統計
+func (l *AssemblyLine) finish() Stats {
+	l.stopMu.Lock()
+	defer l.stopMu.Unlock()
+	l.submitMu.Lock()
+	running := l.accepting
+	l.accepting = false
+	l.submitMu.Unlock()
+	if !running {
+		return l.last
+	}
+	l.done(1)
+	atomic.StoreInt64(&l.held, 0)
+
+	l.wg.Wait()
+	close(l.stopDelays)
+	if l.dashboard != nil {
+		l.dashboard.finish()
+	}
+
+	s := l.stats(time.Since(l.startTime))
+	s.Submitted = l.submitted
+	// 員工全部下班或故障未恢復時, 派發器及延遲佇列中剩下的物品
+	s.Unfinished = int(atomic.LoadInt64(&l.pending))
+	if l.collector != nil {
+		l.collector.flush()
+		l.sinkClosed = l.resultSink != nil
+		s.Results = recent(l.collector.results, l.keep)
+		s.DroppedResults = l.collector.emitted - len(s.Results)
+		if l.reorderWindow > 0 {
+			seq := l.collector.stats
+			seq.Window = l.reorderWindow
+			s.Sequencer = &seq
+		}
+	}
+	if l.hasChangeover() && l.keep < 0 {
+		s.FIFOChangeoverTime = estimateFIFOChangeover(l.arrivals, l.employees, s.avgDurations())
+	}
+	l.last = s
+	return s
+}
+
+func (l *AssemblyLine) reset() {
+	l.batchSizes = nil
+	if l.batchSize > 1 {
+		l.batchSizes = make(map[int]int)
+	}
+	l.types = make(map[string]*TypeStats)
+	l.classes = nil
+	if l.shares != nil {
+		l.classes = make(map[string]*ClassStats)
+	}
+	l.timeline = nil
+	l.collector = nil
+	l.delayed = 0
+	l.late = 0
+	l.lateness = 0
+	l.maxLateness = 0
+	l.duplicates = 0
+	l.rejected = 0
+	l.vetoed = 0
+	if l.resultOrder != nil || l.resultSink != nil {
+		order := CompletionOrder
+		if l.resultOrder != nil {
+			order = *l.resultOrder
+		}
+		sink := l.resultSink
+		if l.sinkClosed {
+			sink = nil
+		}
+		l.collector = newResultCollector(order, sink)
+		l.collector.keep = l.keep
+		l.collector.window = l.reorderWindow
+		l.collector.workers = func() int { return int(atomic.LoadInt64(&l.working)) }
+	}
+	l.changeovers = 0
+	l.changeoverDur = 0
+	l.crashes = 0
+	l.reassigned = 0
+	l.lostTime = 0
+	for _, emp := range l.employees {
+		emp.lastType = ""
+		emp.attempts = 0
+		emp.completed = 0
+		emp.crashes = 0
+		emp.waited = 0
+		emp.blocked = 0
+		emp.setStatus(statusIdle, nil)
+		emp.busy = 0
+		emp.unavailable = 0
+		emp.offAt = 0
+		emp.sinceBreak = 0
+		emp.workingSince = l.startTime
+	}
+}
+
+func (l *AssemblyLine) hasChangeover() bool {
+	for _, emp := range l.employees {
+		if len(emp.Changeover) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// unfinished 回傳已提交但尚未完成的件數, 不含保留到停止的那一件
+func (l *AssemblyLine) unfinished() int {
+	return max(int(atomic.LoadInt64(&l.pending)-atomic.LoadInt64(&l.held)), 0)
+}
+
+// done 標記 n 件物品已完成, 全部完成時關閉派發器
+func (l *AssemblyLine) done(n int) {
+	if atomic.AddInt64(&l.pending, -int64(n)) == 0 {
+		l.dispatcher.Close()
+	}
+}
+
+// work 員工不斷取出一批同類物品處理, 直到派發器清空、下班或故障離開
+func (l *AssemblyLine) work(e *Employee) {
+	var carry *submission
+	for {
+		first := carry
+		carry = nil
+		if first == nil {
+			if !l.awaitShift(e) {
+				return
+			}
+			item, err := l.next(e)
+			if errors.Is(err, context.DeadlineExceeded) {
+				continue
+			}
+			if err != nil {
+				return
+			}
+			first = item
+		}
+
+		batch := []*submission{first}
+		if l.batchSize > 1 {
+			batch, carry = l.fillBatch(e, batch)
+		}
+
+		busyStart, waited, blocked := time.Now(), e.waited, e.blocked
+		crashed := l.process(e, batch)
+		// 限流及重排緩衝區等待的時間不算忙碌
+		busy := time.Since(busyStart) - (e.waited - waited) - (e.blocked - blocked)
+		e.busy += busy
+		e.setIdle(busy)
+		l.onIdle(e)
+		e.sinceBreak += len(batch)
+
+		if crashed && !l.awaitRecovery(e) {
+			if carry != nil {
+				l.requeue(e, carry)
+			}
+			return
+		}
+	}
+}
+
+// next 等待下一件物品, 最多等到員工下班
+func (l *AssemblyLine) next(e *Employee) (*submission, error) {
+	ctx := context.Background()
+	if end, ok := e.Schedule.deadline(l.startTime); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, end)
+		defer cancel()
+	}
+	item, err := l.dispatcher.Next(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	return l.take(item.(*submission)), nil
+}
+
+// fillBatch 在 batchMaxWait 內補滿同類物品,
+// 遇到不同類物品時停止並將它留給下一批
+func (l *AssemblyLine) fillBatch(e *Employee, batch []*submission) ([]*submission, *submission) {
+	kind := itemType(batch[0])
+	ctx, cancel := context.WithTimeout(context.Background(), l.batchMaxWait)
+	defer cancel()
+
+	for len(batch) < l.batchSize {
+		item, err := l.dispatcher.Next(ctx, e)
+		if err != nil {
+			return batch, nil
+		}
+		s := l.take(item.(*submission))
+		if itemType(s) != kind {
+			return batch, s
+		}
+		batch = append(batch, s)
+	}
+	return batch, nil
+}
+
+// changeoverTo 員工切換到不同類型物品前先花費準備時間
+func (l *AssemblyLine) changeoverTo(e *Employee, kind string) {
+	cost := e.Changeover.Cost(e.lastType, kind)
+	if cost <= 0 {
+		return
+	}
+	e.setStatus(statusChangeover, nil)
+	fmt.Fprintf(l.out, "[%s] 員工 #%d 切換 %s -> %s (準備: %v)\n",
+		time.Now().Format(timeLayout),
+		e.ID,
+		e.lastType,
+		kind,
+		cost)
+	time.Sleep(cost)
+
+	l.mu.Lock()
+	l.changeovers++
+	l.changeoverDur += cost
+	l.mu.Unlock()
+}
+
+// typeStats 取得類型統計, 呼叫時需持有鎖
+func (l *AssemblyLine) typeStats(kind string) *TypeStats {
+	ts, ok := l.types[kind]
+	if !ok {
+		ts = &TypeStats{}
+		l.types[kind] = ts
+	}
+	return ts
+}
+
+// record 累計每種物品的處理數量及時間
+func (l *AssemblyLine) record(kind string, n int, busy time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ts := l.typeStats(kind)
+	ts.Processed += n
+	ts.Busy += busy
+}
+
+// recordWait 累計每種物品的限流等待時間
+func (l *AssemblyLine) recordWait(kind string, wait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.typeStats(kind).Wait += wait
+}
+
+// process 處理一批物品並打印開始及結束紀錄, 員工中途故障時回傳 true
+func (l *AssemblyLine) process(e *Employee, batch []*submission) bool {
+	if l.batchSize > 1 {
+		l.mu.Lock()
+		l.batchSizes[len(batch)]++
+		l.mu.Unlock()
+	}
+
+	items := make([]Item, len(batch))
+	for i, s := range batch {
+		items[i] = s.Item
+	}
+	kind := itemType(items[0])
+	e.setStatus(statusThrottled, items[0])
+	if w := l.throttle(kind, len(batch)); w > 0 {
+		e.waited += w
+		l.recordWait(kind, w)
+	}
+	l.changeoverTo(e, kind)
+	e.lastType = kind
+	speed := e.Speed.Factor(kind)
+
+	e.setStatus(statusProcessing, items[0])
+	now := time.Now()
+	for _, s := range batch {
+		l.recordLateness(s, now)
+		if l.wal != nil {
+			l.wal.Started(s.walID)
+		}
+	}
+	if l.crash(e, batch, speed) {
+		return true
+	}
+
+	// 會產生輸出的物品及有掛勾時需逐件處理
+	bp, ok := items[0].(BatchProcessor)
+	if _, producer := items[0].(Producer); len(batch) == 1 || !ok || producer || l.hooked() {
+		run := l.processFunc(speed)
+		for i, s := range batch {
+			// 掛勾可換成其他物品處理, 完成的紀錄仍以提交的物品為準
+			target, err := l.beforeStart(e, s.Item)
+			if err != nil {
+				l.veto(e, s, err)
+				continue
+			}
+			processStart := time.Now()
+			e.setStatus(statusProcessing, target)
+			l.logStart(e, processStart, target)
+			value, err := run(e, target)
+			processEnd := time.Now()
+			l.logFinish(e, processStart, processEnd, target)
+			l.addSpan(e, target, processStart, processEnd, OutcomeCompleted)
+			l.record(kind, 1, processEnd.Sub(processStart))
+			l.recordClass(items[i:i+1], processEnd.Sub(processStart))
+			l.afterFinish(e, target, value, err)
+			l.complete(e, s, value, err, processEnd)
+		}
+		return false
+	}
+
+	processStart := time.Now()
+	for _, item := range items {
+		l.logStart(e, processStart, item)
+	}
+	processBatchWithSpeed(bp, items, speed)
+	processEnd := time.Now()
+	l.record(kind, len(batch), processEnd.Sub(processStart))
+	l.recordClass(items, processEnd.Sub(processStart))
+	for _, s := range batch {
+		l.logFinish(e, processStart, processEnd, s.Item)
+		l.addSpan(e, s.Item, processStart, processEnd, OutcomeCompleted)
+		l.complete(e, s, nil, nil, processEnd)
+	}
+	return false
+}
+
+// complete 記錄一次提交處理完成, 已計入過的完成回報不再計算
+func (l *AssemblyLine) complete(e *Employee, s *submission, value any, err error, end time.Time) {
+	if !l.credit(s) {
+		l.rejectCompletion(e, s.Item)
+		return
+	}
+	e.completed++
+	e.recordCompleted(itemType(s.Item))
+	l.finishItem(e, s, value, err, end)
+}
+
+// finishItem 寫入預寫日誌、收集結果並標記物品結束
+func (l *AssemblyLine) finishItem(e *Employee, s *submission, value any, err error, end time.Time) {
+	if l.wal != nil {
+		l.wal.Completed(s.walID, err)
+	}
+	if l.collector != nil {
+		e.blocked += l.collector.add(Result{
+			Seq:      s.seq,
+			Item:     s.Item,
+			Value:    value,
+			Err:      err,
+			Employee: e.ID,
+			Finished: end,
+		})
+	}
+	l.done(1)
+}
+
+func (l *AssemblyLine) logStart(e *Employee, start time.Time, item Item) {
+	fmt.Fprintf(l.out, "[%s] 員工 #%d 開始處理 %s\n",
+		start.Format(timeLayout),
+		e.ID,
+		item.String())
+}
+
+func (l *AssemblyLine) logFinish(e *Employee, start, end time.Time, item Item) {
+	fmt.Fprintf(l.out, "[%s] 員工 #%d 完成處理 %s (耗時: %v)\n",
+		end.Format(timeLayout),
+		e.ID,
+		item.String(),
+		end.Sub(start))
+}
+
+// EmployeeStats 單一員工的統計
+type EmployeeStats struct {
+	ID        int
+	Processed int
+	// Busy 處理物品 (含切換準備) 的時間
+	Busy time.Duration
+	// Unavailable 休息、故障恢復、尚未上班或已下班的時間
+	Unavailable time.Duration
+	// Idle 在班但沒有物品可處理的時間
+	Idle time.Duration
+	// Waiting 等待限流的時間
+	Waiting time.Duration
+	// Blocked 等待重排緩衝區空出位置或結果 sink 接收的時間
+	Blocked time.Duration
+	Crashes int
+}
+
+// TypeStats 單一物品類型的統計
+type TypeStats struct {
+	Processed int
+	Busy      time.Duration
+	// Wait 開始處理前等待限流的時間
+	Wait time.Duration
+}
+
+// Avg 平均每件的處理時間
+func (t TypeStats) Avg() time.Duration {
+	if t.Processed == 0 {
+		return 0
+	}
+	return t.Busy / time.Duration(t.Processed)
+}
+
+// Stats 一次執行的統計結果
+type Stats struct {
+	Start      time.Time
+	TotalTime  time.Duration
+	Submitted  int
+	Employees  []EmployeeStats
+	Types      map[string]TypeStats
+	BatchSizes map[int]int
+	// Classes 啟用 WithFairShare 時各分組的統計
+	Classes map[string]ClassStats
+
+	Changeovers    int
+	ChangeoverTime time.Duration
+	// FIFOChangeoverTime 同一批物品以先進先出派發時估計的切換時間, 只在保留全部歷史時估計
+	FIFOChangeoverTime time.Duration
+
+	Crashes    int
+	Reassigned int
+	// LostTime 故障時白做的處理時間加上恢復時間
+	LostTime time.Duration
+
+	// Delayed 延遲或排程的物品數, Late 放行時沒有員工閒置、超過排程時間才開始的物品數
+	Delayed int
+	Late    int
+	// Lateness 延誤物品比排程時間晚開始的總時間
+	Lateness    time.Duration
+	MaxLateness time.Duration
+
+	// Duplicates 因冪等鍵重複而略過的提交數
+	Duplicates int
+	// RejectedCompletions 已計入過的物品再次回報完成而不計算的次數
+	RejectedCompletions int
+	// Vetoed 被 BeforeStart 掛勾否決而沒有處理的物品數
+	Vetoed int
+	// Unfinished 停止時仍未完成的提交數, 例如員工全部下班或故障後不再回來
+	Unfinished int
+
+	// Results 啟用結果收集時, 依設定順序排列的處理結果;
+	// DroppedResults 超過 WithHistory 的筆數而沒有保留的結果數
+	Results        []Result
+	DroppedResults int
+	// Sequencer 啟用 WithSequencer 時的重排緩衝區統計
+	Sequencer *SequencerStats
+	// Timeline 每段處理的時間軸, 依完成時間排列; 長時間運行時只有 WithHistory 保留的部分
+	Timeline []Span
+}
+
+// TotalProcessed 所有員工處理的物品總數
+func (s Stats) TotalProcessed() int {
+	total := 0
+	for _, e := range s.Employees {
+		total += e.Processed
+	}
+	return total
+}
+
+func (l *AssemblyLine) stats(totalTime time.Duration) Stats {
+	s := Stats{
+		Start:          l.startTime,
+		TotalTime:      totalTime,
+		Types:          make(map[string]TypeStats, len(l.types)),
+		BatchSizes:     l.batchSizes,
+		Changeovers:    l.changeovers,
+		ChangeoverTime: l.changeoverDur,
+		Crashes:        l.crashes,
+		Reassigned:     l.reassigned,
+		LostTime:       l.lostTime,
+		Timeline:       recent(l.timeline, l.keep),
+		Delayed:        l.delayed,
+		Late:           l.late,
+		Lateness:       l.lateness,
+		MaxLateness:    l.maxLateness,
+		Duplicates:     l.duplicates,
+	}
+	s.RejectedCompletions = l.rejected
+	s.Vetoed = l.vetoed
+	for kind, ts := range l.types {
+		s.Types[kind] = *ts
+	}
+	if l.classes != nil {
+		s.Classes = make(map[string]ClassStats, len(l.classes))
+		for class, cs := range l.classes {
+			s.Classes[class] = *cs
+		}
+	}
+	for _, emp := range l.employees {
+		es := EmployeeStats{
+			ID:          emp.ID,
+			Processed:   emp.completed,
+			Busy:        emp.busy,
+			Unavailable: emp.unavailable,
+			Waiting:     emp.waited,
+			Blocked:     emp.blocked,
+			Crashes:     emp.crashes,
+		}
+		if emp.offAt > 0 {
+			es.Unavailable += totalTime - emp.offAt
+		}
+		es.Idle = totalTime - es.Busy - es.Unavailable - es.Waiting - es.Blocked
+		if es.Idle < 0 {
+			es.Idle = 0
+		}
+		s.Employees = append(s.Employees, es)
+	}
+	return s
+}
+
+// typeNames 依名稱排序的物品類型
+func (s Stats) typeNames() []string {
+	names := make([]string, 0, len(s.Types))
+	for kind := range s.Types {
+		names = append(names, kind)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// avgDurations 每種物品的平均處理時間
+func (s Stats) avgDurations() map[string]time.Duration {
+	avg := make(map[string]time.Duration, len(s.Types))
+	for kind, ts := range s.Types {
+		avg[kind] = ts.Avg()
+	}
+	return avg
+}
+
+// Print 打印統計結果
+func (s Stats) Print(w io.Writer) {
+	fmt.Fprintln(w, "\n========== 統計結果 ==========")
+	fmt.Fprintf(w, "總處理時間: %v\n", s.TotalTime)
+	scheduled := false
+	for _, e := range s.Employees {
+		if e.Unavailable > 0 {
+			scheduled = true
+		}
+	}
+	for _, e := range s.Employees {
+		if scheduled {
+			fmt.Fprintf(w, "員工 #%d 處理了 %d 件物品 (忙碌: %v, 閒置: %v, 不在班: %v)\n",
+				e.ID, e.Processed, e.Busy.Round(time.Millisecond),
+				e.Idle.Round(time.Millisecond), e.Unavailable.Round(time.Millisecond))
+		} else {
+			fmt.Fprintf(w, "員工 #%d 處理了 %d 件物品\n", e.ID, e.Processed)
+		}
+	}
+	fmt.Fprintf(w, "總共處理: %d 件物品\n", s.TotalProcessed())
+	if s.Unfinished > 0 {
+		fmt.Fprintf(w, "未處理: %d 件物品\n", s.Unfinished)
+	}
+
+	if len(s.BatchSizes) > 0 {
+		sizes := make([]int, 0, len(s.BatchSizes))
+		for size := range s.BatchSizes {
+			sizes = append(sizes, size)
+		}
+		sort.Ints(sizes)
+		parts := make([]string, 0, len(sizes))
+		for _, size := range sizes {
+			parts = append(parts, fmt.Sprintf("%d 件 x %d 批", size, s.BatchSizes[size]))
+		}
+		fmt.Fprintf(w, "批次大小分佈: %s\n", strings.Join(parts, ", "))
+	}
+
+	if s.Changeovers > 0 || s.FIFOChangeoverTime > 0 {
+		fmt.Fprintf(w, "切換類型: %d 次, 共 %v\n", s.Changeovers, s.ChangeoverTime)
+		fmt.Fprintf(w, "先進先出估計切換時間: %v, 節省: %v\n",
+			s.FIFOChangeoverTime, s.FIFOChangeoverTime-s.ChangeoverTime)
+	}
+
+	var waiting time.Duration
+	for _, e := range s.Employees {
+		waiting += e.Waiting
+	}
+	if waiting > 0 {
+		fmt.Fprintf(w, "限流等待: %v\n", waiting.Round(time.Millisecond))
+		for _, kind := range s.typeNames() {
+			if ts := s.Types[kind]; ts.Wait > 0 {
+				fmt.Fprintf(w, "  %s 等待 %v, 處理 %v\n", kind,
+					ts.Wait.Round(time.Millisecond), ts.Busy.Round(time.Millisecond))
+			}
+		}
+	}
+
+	if q := s.Sequencer; q != nil {
+		var avg time.Duration
+		if len(s.Results) > 0 {
+			avg = q.TotalWait / time.Duration(len(s.Results))
+		}
+		fmt.Fprintf(w, "重排緩衝 (視窗 %d): 平均等待 %v, 最長等待 %v, 員工被阻擋 %v\n",
+			q.Window, avg.Round(time.Millisecond), q.MaxWait.Round(time.Millisecond),
+			q.Blocked.Round(time.Millisecond))
+		if q.Overflows > 0 {
+			fmt.Fprintf(w, "  超出視窗暫存: %d 次\n", q.Overflows)
+		}
+	}
+
+	if s.Duplicates > 0 {
+		fmt.Fprintf(w, "重複提交略過: %d 件\n", s.Duplicates)
+	}
+
+	if s.Delayed > 0 {
+		fmt.Fprintf(w, "排程物品: %d 件, 延誤開始: %d 件", s.Delayed, s.Late)
+		if s.Late > 0 {
+			fmt.Fprintf(w, " (平均延誤 %v, 最長延誤 %v)",
+				(s.Lateness / time.Duration(s.Late)).Round(time.Millisecond),
+				s.MaxLateness.Round(time.Millisecond))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(s.Classes) > 0 {
+		var busy time.Duration
+		for _, cs := range s.Classes {
+			busy += cs.Busy
+		}
+		fmt.Fprintln(w, "公平分配:")
+		for _, class := range s.classNames() {
+			cs := s.Classes[class]
+			share := 0.0
+			if busy > 0 {
+				share = float64(cs.Busy) / float64(busy) * 100
+			}
+			fmt.Fprintf(w, "  %s (權重 %g): 處理 %d 件, 佔員工時間 %.1f%%, 吞吐量 %.2f 件/秒\n",
+				class, cs.Weight, cs.Processed, share, cs.Throughput(s.TotalTime))
+		}
+	}
+
+	if s.Crashes > 0 {
+		fmt.Fprintf(w, "故障: %d 次, 重新分派: %d 件物品, 損失時間: %v\n",
+			s.Crashes, s.Reassigned, s.LostTime.Round(time.Millisecond))
+		for _, e := range s.Employees {
+			if e.Crashes > 0 {
+				fmt.Fprintf(w, "  員工 #%d 故障 %d 次\n", e.ID, e.Crashes)
+			}
+		}
+	}
+
+	if s.Vetoed > 0 {
+		fmt.Fprintf(w, "否決: %d 件物品\n", s.Vetoed)
+	}
+	if s.RejectedCompletions > 0 {
+		fmt.Fprintf(w, "重複回報完成: %d 次, 未重複計算\n", s.RejectedCompletions)
+	}
+	if err := s.Reconcile(); err != nil {
+		fmt.Fprintf(w, "核對失敗: %v\n", err)
+	} else {
+		fmt.Fprintf(w, "核對: 提交 %d 件, 完成 %d 件, 否決 %d 件, 未完成 %d 件, 一致\n",
+			s.Submitted, s.TotalProcessed(), s.Vetoed, s.Unfinished)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testItem 測試用物品, 處理時間及類型可自訂
+type testItem struct {
+	kind      string
+	id        int
+	d         time.Duration
+	processed *int32
+}
+
+func (i *testItem) Process() {
+	time.Sleep(i.d)
+	if i.processed != nil {
+		atomic.AddInt32(i.processed, 1)
+	}
+}
+
+func (i *testItem) String() string {
+	return fmt.Sprintf("%s #%d", i.kind, i.id)
+}
+
+func (i *testItem) Type() string {
+	return i.kind
+}
+
+func (i *testItem) EstimatedDuration() time.Duration {
+	return i.d
+}
+
+// batchTestItem 支援批次處理的測試物品
+type batchTestItem struct {
+	testItem
+	batches *int32
+}
+
+func (i *batchTestItem) ProcessBatch(items []Item) {
+	atomic.AddInt32(i.batches, 1)
+	for _, item := range items {
+		item.Process()
+	}
+}
+
+// newTestItems 依序創建每種類型各 n 件測試物品
+func newTestItems(n int, d time.Duration, kinds ...string) []Item {
+	items := make([]Item, 0, n*len(kinds))
+	for _, kind := range kinds {
+		for i := 0; i < n; i++ {
+			items = append(items, &testItem{kind: kind, id: i + 1, d: d})
+		}
+	}
+	return items
+}
+
+// TestItemType 驗證物品類型名稱
+func TestItemType(t *testing.T) {
+	tests := []struct {
+		item Item
+		want string
+	}{
+		{&Item1{ID: 1}, "Item1"},
+		{&Item2{ID: 1}, "Item2"},
+		{&Item3{ID: 1}, "Item3"},
+		{&testItem{kind: "custom"}, "custom"},
+	}
+	for _, tt := range tests {
+		if got := itemType(tt.item); got != tt.want {
+			t.Errorf("itemType(%s) = %q, want %q", tt.item, got, tt.want)
+		}
+	}
+}
+
+// TestAssemblyLine_Run 驗證流水線處理所有物品
+func TestAssemblyLine_Run(t *testing.T) {
+	line := NewAssemblyLine(5)
+	stats := line.Run(newTestItems(10, time.Millisecond, "A", "B", "C"))
+
+	if got := stats.TotalProcessed(); got != 30 {
+		t.Errorf("TotalProcessed = %d, want 30", got)
+	}
+	if len(stats.Employees) != 5 {
+		t.Errorf("len(Employees) = %d, want 5", len(stats.Employees))
+	}
+	if stats.BatchSizes != nil {
+		t.Errorf("BatchSizes = %v, want nil without batch mode", stats.BatchSizes)
+	}
+}
+
+// TestAssemblyLine_BatchSameType 驗證批次只包含同類物品且不超過上限
+func TestAssemblyLine_BatchSameType(t *testing.T) {
+	// 單一員工依序取物品, 每批應剛好湊滿 3 件
+	line := NewAssemblyLine(1, WithBatch(3, 50*time.Millisecond))
+	stats := line.Run(newTestItems(6, time.Millisecond, "A", "B"))
+
+	if got := stats.TotalProcessed(); got != 12 {
+		t.Errorf("TotalProcessed = %d, want 12", got)
+	}
+	if stats.BatchSizes[3] != 4 || len(stats.BatchSizes) != 1 {
+		t.Errorf("BatchSizes = %v, want map[3:4]", stats.BatchSizes)
+	}
+}
+
+// TestAssemblyLine_BatchTypeSwitch 驗證遇到不同類物品時提早結束批次
+func TestAssemblyLine_BatchTypeSwitch(t *testing.T) {
+	items := []Item{
+		&testItem{kind: "A", id: 1},
+		&testItem{kind: "B", id: 1},
+		&testItem{kind: "B", id: 2},
+		&testItem{kind: "A", id: 2},
+	}
+	line := NewAssemblyLine(1, WithBatch(4, 50*time.Millisecond))
+	stats := line.Run(items)
+
+	if stats.BatchSizes[1] != 2 || stats.BatchSizes[2] != 1 {
+		t.Errorf("BatchSizes = %v, want map[1:2 2:1]", stats.BatchSizes)
+	}
+	if got := stats.TotalProcessed(); got != 4 {
+		t.Errorf("TotalProcessed = %d, want 4", got)
+	}
+}
+
+// TestAssemblyLine_BatchProcessor 驗證實作 BatchProcessor 的物品會整批處理
+func TestAssemblyLine_BatchProcessor(t *testing.T) {
+	var batches, processed int32
+	items := make([]Item, 0, 4)
+	for i := 0; i < 4; i++ {
+		items = append(items, &batchTestItem{
+			testItem: testItem{kind: "A", id: i + 1, processed: &processed},
+			batches:  &batches,
+		})
+	}
+
+	line := NewAssemblyLine(1, WithBatch(2, 50*time.Millisecond))
+	line.Run(items)
+
+	if batches != 2 {
+		t.Errorf("ProcessBatch called %d times, want 2", batches)
+	}
+	if processed != 4 {
+		t.Errorf("processed = %d, want 4", processed)
+	}
+}
+
+// TestBatchDuration 驗證批次處理時間
+func TestBatchDuration(t *testing.T) {
+	if got := batchDuration(100*time.Millisecond, 1); got != 100*time.Millisecond {
+		t.Errorf("batchDuration(100ms, 1) = %v, want 100ms", got)
+	}
+	if got := batchDuration(100*time.Millisecond, 3); got != 200*time.Millisecond {
+		t.Errorf("batchDuration(100ms, 3) = %v, want 200ms", got)
+	}
+}
+
+// TestAssemblyLine_StartSubmitStop 驗證長時間運行時持續加入物品
+func TestAssemblyLine_StartSubmitStop(t *testing.T) {
+	var processed int32
+	line := NewAssemblyLine(2, WithQueueCapacity(4))
+	if err := line.Submit(&testItem{kind: "A"}); err != ErrStopped {
+		t.Errorf("Submit before Start error = %v, want ErrStopped", err)
+	}
+
+	line.Start()
+	for i := 0; i < 3; i++ {
+		// 物品暫時處理完不應提早關閉流水線
+		time.Sleep(5 * time.Millisecond)
+		if err := line.Submit(&testItem{kind: "A", id: i, d: time.Millisecond, processed: &processed}); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+	stats := line.Stop()
+
+	if stats.Submitted != 3 || processed != 3 {
+		t.Errorf("Submitted = %d, processed = %d, want 3", stats.Submitted, processed)
+	}
+	if err := line.Submit(&testItem{kind: "A"}); err != ErrStopped {
+		t.Errorf("Submit after Stop error = %v, want ErrStopped", err)
+	}
+}
+
+// TestAssemblyLine_StopIdempotent 驗證未啟動時 Stop 回傳零值, 重複 Stop 回傳上次的統計
+func TestAssemblyLine_StopIdempotent(t *testing.T) {
+	line := NewAssemblyLine(1)
+	if stats := line.Stop(); stats.Submitted != 0 || stats.TotalProcessed() != 0 {
+		t.Errorf("Stop before Start = %+v, want zero", stats)
+	}
+
+	line.Start()
+	line.Submit(&testItem{kind: "A", id: 1})
+	first := line.Stop()
+	if again := line.Stop(); again.Submitted != 1 || again.TotalTime != first.TotalTime {
+		t.Errorf("second Stop Submitted = %d, TotalTime = %v, want 1 and %v", again.Submitted, again.TotalTime, first.TotalTime)
+	}
+}
@@ -1,28 +1,64 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"math/rand"
-	"sync"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
 type Employee struct {
-	ID             int
-	ProcessedCount int
-	mu             sync.Mutex
+	ID int
+	// Changeover 切換物品類型的準備時間
+	Changeover Changeover
+	// Speed 處理各類型物品的速度倍率
+	Speed Skill
+	// Schedule 班表及休息規則
+	Schedule Schedule
+	// Failure 故障注入設定
+	Failure Failure
+
+	// state 目前狀態的快照, 每次更新時整份替換, 其他 goroutine 可直接讀取
+	state atomic.Pointer[EmployeeState]
+
+	// 以下為單次執行的狀態, 只由員工自己的 goroutine 存取
+	lastType     string
+	busy         time.Duration
+	unavailable  time.Duration
+	offAt        time.Duration
+	sinceBreak   int
+	workingSince time.Time
+	attempts     int
+	completed    int
+	crashes      int
+	waited       time.Duration
+	blocked      time.Duration
 }
 
 func (e *Employee) IncrementCount() {
-	e.mu.Lock()
-	defer e.mu.Unlock()
-	e.ProcessedCount++
+	e.update(func(s *EmployeeState) {
+		s.Processed++
+	})
 }
 
 func (e *Employee) GetCount() int {
-	e.mu.Lock()
-	defer e.mu.Unlock()
-	return e.ProcessedCount
+	return e.State().Processed
+}
+
+const (
+	item1Duration = 100 * time.Millisecond
+	item2Duration = 150 * time.Millisecond
+	item3Duration = 200 * time.Millisecond
+)
+
+// batchDuration 同批物品共用準備時間, 第一件之後每件只需一半時間
+func batchDuration(d time.Duration, n int) time.Duration {
+	return d + time.Duration(n-1)*d/2
 }
 
 type Item1 struct {
@@ -30,7 +66,23 @@ type Item1 struct {
 }
 
 func (i *Item1) Process() {
-	time.Sleep(100 * time.Millisecond)
+	time.Sleep(item1Duration)
+}
+
+func (i *Item1) ProcessBatch(items []Item) {
+	time.Sleep(batchDuration(item1Duration, len(items)))
+}
+
+func (i *Item1) ProcessBatchWithSpeed(items []Item, speed float64) {
+	time.Sleep(scaleDuration(batchDuration(item1Duration, len(items)), speed))
+}
+
+func (i *Item1) ProcessWithSpeed(speed float64) {
+	time.Sleep(scaleDuration(item1Duration, speed))
+}
+
+func (i *Item1) EstimatedDuration() time.Duration {
+	return item1Duration
 }
 
 func (i *Item1) String() string {
@@ -42,7 +94,23 @@ type Item2 struct {
 }
 
 func (i *Item2) Process() {
-	time.Sleep(150 * time.Millisecond)
+	time.Sleep(item2Duration)
+}
+
+func (i *Item2) ProcessBatch(items []Item) {
+	time.Sleep(batchDuration(item2Duration, len(items)))
+}
+
+func (i *Item2) ProcessBatchWithSpeed(items []Item, speed float64) {
+	time.Sleep(scaleDuration(batchDuration(item2Duration, len(items)), speed))
+}
+
+func (i *Item2) ProcessWithSpeed(speed float64) {
+	time.Sleep(scaleDuration(item2Duration, speed))
+}
+
+func (i *Item2) EstimatedDuration() time.Duration {
+	return item2Duration
 }
 
 func (i *Item2) String() string {
@@ -54,7 +122,23 @@ type Item3 struct {
 }
 
 func (i *Item3) Process() {
-	time.Sleep(200 * time.Millisecond)
+	time.Sleep(item3Duration)
+}
+
+func (i *Item3) ProcessBatch(items []Item) {
+	time.Sleep(batchDuration(item3Duration, len(items)))
+}
+
+func (i *Item3) ProcessBatchWithSpeed(items []Item, speed float64) {
+	time.Sleep(scaleDuration(batchDuration(item3Duration, len(items)), speed))
+}
+
+func (i *Item3) ProcessWithSpeed(speed float64) {
+	time.Sleep(scaleDuration(item3Duration, speed))
+}
+
+func (i *Item3) EstimatedDuration() time.Duration {
+	return item3Duration
 }
 
 func (i *Item3) String() string {
@@ -67,80 +151,298 @@ type Item interface {
 	String() string
 }
 
-func main() {
-	startTime := time.Now()
+// BatchProcessor 可選介面, 實作者可一次處理多件同類物品
+type BatchProcessor interface {
+	ProcessBatch(items []Item)
+}
 
-	// 創建物品
-	items := make([]Item, 0, 30)
-	for i := 0; i < 10; i++ {
+// SpeedProcessor 可選介面, 依員工速度倍率調整處理時間
+type SpeedProcessor interface {
+	ProcessWithSpeed(speed float64)
+}
+
+// SpeedBatchProcessor 可選介面, 依員工速度倍率調整整批的處理時間
+type SpeedBatchProcessor interface {
+	ProcessBatchWithSpeed(items []Item, speed float64)
+}
+
+// Estimator 可選介面, 回傳物品預估的處理時間
+type Estimator interface {
+	EstimatedDuration() time.Duration
+}
+
+// numEmployees 流水線上的員工人數
+const numEmployees = 5
+
+// itemTypes 三種物品的類型名稱
+var itemTypes = []string{"Item1", "Item2", "Item3"}
+
+// itemDurations 三種物品的處理時間
+var itemDurations = Durations{"Item1": item1Duration, "Item2": item2Duration, "Item3": item3Duration}
+
+// newItems 創建三種物品各 n 件
+func newItems(n int) []Item {
+	items := make([]Item, 0, 3*n)
+	for i := 0; i < n; i++ {
 		items = append(items, &Item1{ID: i + 1})
 	}
-	for i := 0; i < 10; i++ {
+	for i := 0; i < n; i++ {
 		items = append(items, &Item2{ID: i + 1})
 	}
-	for i := 0; i < 10; i++ {
+	for i := 0; i < n; i++ {
 		items = append(items, &Item3{ID: i + 1})
 	}
+	return items
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(runDiff(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "coordinator" {
+		os.Exit(runCoordinator(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "worker" {
+		os.Exit(runWorker(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	seed := flag.Int64("seed", time.Now().UnixNano(), "隨機種子, 用於打亂物品順序及故障注入")
+	batchSize := flag.Int("batch", 1, "每位員工一次最多處理幾件同類物品")
+	batchWait := flag.Duration("batch-wait", 50*time.Millisecond, "湊齊一批的最長等待時間")
+	dispatch := flag.String("dispatch", "fifo", "派發策略: fifo, spt, lpt, affinity, steal, skill")
+	changeover := flag.Duration("changeover", 0, "員工切換物品類型的準備時間")
+	breakEvery := flag.Int("break-every", 0, "每處理幾件物品休息一次")
+	breakAfter := flag.Duration("break-after", 0, "連續工作多久後休息一次")
+	breakLength := flag.Duration("break-length", 0, "每次休息的時間")
+	crashRate := flag.Float64("crash-rate", 0, "員工每次開始處理時故障的機率")
+	recovery := flag.Duration("recovery", 300*time.Millisecond, "故障員工恢復所需時間, 0 表示不再回來")
+	rate := flag.Float64("rate", 0, "整條流水線每秒最多開始處理幾件物品")
+	typeRates := flag.String("type-rate", "", "各類型每秒最多開始處理幾件, 例如 Item2=5,Item3=2")
+	results := flag.String("results", "", "收集處理結果並打印輸出順序: completion, submission")
+	window := flag.Int("window", 0, "依提交順序輸出時最多暫存幾件提前完成的物品, 0 表示不限")
+	timelineCSV := flag.String("timeline-csv", "", "將時間軸輸出為 CSV 檔")
+	timelineSVG := flag.String("timeline-svg", "", "將時間軸輸出為 SVG 甘特圖")
+	live := flag.Bool("dashboard", false, "在終端機顯示即時面板, 輸出不是終端機時改為逐行紀錄")
+	speeds := flag.String("speeds", "", "每位員工的速度倍率, 以逗號分隔, 例如 1.5,1.2,1,0.8,0.6")
+	reportJSON := flag.String("report-json", "", "將執行報告輸出為 JSON 檔")
+	reportMD := flag.String("report-md", "", "將執行報告輸出為 Markdown 檔")
+	fair := flag.String("fair", "", "依權重公平分配員工時間, 例如 Item1=1,Item2=1,Item3=1")
+	walPath := flag.String("wal", "", "預寫日誌檔案, 啟動時先處理上次未完成的物品")
+	compare := flag.Bool("compare", false, "以模擬時間比較所有派發策略, 不實際處理物品")
+	serve := flag.String("http", "", "以 HTTP 接收物品信封的位址, 例如 127.0.0.1:8080")
+	dedup := flag.Duration("dedup", 0, "相同冪等鍵的物品在此時間內只處理一次, 例如 -http 的重試")
+	flag.Parse()
+
+	opts := []Option{
+		WithOutput(os.Stdout),
+		WithSeed(*seed),
+		WithBatch(*batchSize, *batchWait),
+		WithDurations(itemDurations),
+	}
+	if *live && isTerminal(os.Stdout) {
+		opts = append(opts, WithDashboard(os.Stdout, 100*time.Millisecond))
+	}
+	if *changeover > 0 {
+		opts = append(opts, WithChangeover(UniformChangeover(itemTypes, *changeover)))
+	}
+	if *speeds != "" {
+		var skills []Skill
+		for _, f := range strings.Split(*speeds, ",") {
+			speed, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+			if err != nil || speed <= 0 {
+				fmt.Fprintf(os.Stderr, "無效的速度倍率: %s\n", f)
+				os.Exit(2)
+			}
+			skills = append(skills, UniformSkill(itemTypes, speed))
+		}
+		opts = append(opts, WithSkills(skills...))
+	}
+	if *breakLength > 0 {
+		schedules := make([]Schedule, numEmployees)
+		for i := range schedules {
+			schedules[i] = Schedule{BreakEvery: *breakEvery, BreakAfter: *breakAfter, BreakLength: *breakLength}
+		}
+		opts = append(opts, WithSchedules(schedules...))
+	}
+	if *crashRate > 0 {
+		failures := make([]Failure, numEmployees)
+		for i := range failures {
+			failures[i] = Failure{Rate: *crashRate, Recovery: *recovery}
+		}
+		opts = append(opts, WithFailures(failures...))
+	}
+	if *rate > 0 {
+		opts = append(opts, WithRateLimit(*rate, 1))
+	}
+	if *typeRates != "" {
+		for _, f := range strings.Split(*typeRates, ",") {
+			kind, value, _ := strings.Cut(strings.TrimSpace(f), "=")
+			r, err := strconv.ParseFloat(value, 64)
+			if err != nil || r <= 0 {
+				fmt.Fprintf(os.Stderr, "無效的類型限流: %s\n", f)
+				os.Exit(2)
+			}
+			opts = append(opts, WithTypeRateLimit(kind, r, 1))
+		}
+	}
+	if *dedup > 0 {
+		opts = append(opts, WithDedup(*dedup))
+	}
+	if *fair != "" {
+		// 公平分配本身就是派發策略, 不能與其他策略同時使用
+		if *dispatch != "fifo" {
+			fmt.Fprintf(os.Stderr, "-fair 不能與 -dispatch %s 同時使用\n", *dispatch)
+			os.Exit(2)
+		}
+		shares := make(Shares)
+		for _, f := range strings.Split(*fair, ",") {
+			class, value, _ := strings.Cut(strings.TrimSpace(f), "=")
+			w, err := strconv.ParseFloat(value, 64)
+			if err != nil || w <= 0 {
+				fmt.Fprintf(os.Stderr, "無效的權重: %s\n", f)
+				os.Exit(2)
+			}
+			shares[class] = w
+		}
+		opts = append(opts, WithFairShare(shares))
+	}
+	switch *results {
+	case "":
+	case "completion":
+		opts = append(opts, WithResults(CompletionOrder))
+	case "submission":
+		if *window > 0 {
+			opts = append(opts, WithSequencer(*window))
+		} else {
+			opts = append(opts, WithResults(SubmissionOrder))
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "未知的結果順序: %s\n", *results)
+		os.Exit(2)
+	}
+	switch *dispatch {
+	case "fifo":
+	case "spt":
+		opts = append(opts, WithDispatcher(NewSPTDispatcher))
+	case "lpt":
+		opts = append(opts, WithDispatcher(NewLPTDispatcher))
+	case "affinity":
+		opts = append(opts, WithDispatcher(NewAffinityDispatcher))
+	case "steal":
+		opts = append(opts, WithDispatcher(NewWorkStealingDispatcher))
+	case "skill":
+		opts = append(opts, WithDispatcher(NewSkillDispatcher(itemDurations)))
+	default:
+		fmt.Fprintf(os.Stderr, "未知的派發策略: %s\n", *dispatch)
+		os.Exit(2)
+	}
+
+	// 創建物品
+	items := newItems(10)
 
 	// 隨機打亂
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	r := rand.New(rand.NewSource(*seed))
 	r.Shuffle(len(items), func(i, j int) {
 		items[i], items[j] = items[j], items[i]
 	})
 
-	// 創建任務 channel
-	itemChan := make(chan Item, len(items))
-	for _, item := range items {
-		itemChan <- item
-	}
-	close(itemChan)
-
-	// 創建員工
-	employees := make([]*Employee, 5)
-	for i := 0; i < 5; i++ {
-		employees[i] = &Employee{ID: i + 1}
-	}
-
-	// 啟動員工 goroutines
-	var wg sync.WaitGroup
-	for _, emp := range employees {
-		wg.Add(1)
-		go func(e *Employee) {
-			defer wg.Done()
-			for item := range itemChan {
-				processStart := time.Now()
-				fmt.Printf("[%s] 員工 #%d 開始處理 %s\n",
-					processStart.Format("2006-01-02 15:04:05.000"),
-					e.ID,
-					item.String())
-
-				item.Process()
-
-				processEnd := time.Now()
-				duration := processEnd.Sub(processStart)
-				fmt.Printf("[%s] 員工 #%d 完成處理 %s (耗時: %v)\n",
-					processEnd.Format("2006-01-02 15:04:05.000"),
-					e.ID,
-					item.String(),
-					duration)
-
-				e.IncrementCount()
-			}
-		}(emp)
+	var recovered []Item
+	if *walPath != "" {
+		w, pending, err := OpenWAL(*walPath, JSONCodec{Registry: BuiltinItems()})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "開啟預寫日誌失敗: %v\n", err)
+			os.Exit(1)
+		}
+		defer w.Close()
+		if len(pending) > 0 {
+			fmt.Printf("從預寫日誌恢復 %d 件未完成的物品\n", len(pending))
+			recovered = pending
+			items = append(pending, items...)
+		}
+		opts = append(opts, WithWAL(w))
 	}
 
-	wg.Wait()
+	if *serve != "" {
+		if err := runJobServer(*serve, opts, recovered); err != nil {
+			fmt.Fprintf(os.Stderr, "HTTP 服務失敗: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	totalTime := time.Since(startTime)
+	line := NewAssemblyLine(numEmployees, opts...)
+	if *compare {
+		fmt.Printf("以模擬時間比較派發策略 (%d 件物品, %d 位員工, seed %d)\n", len(items), numEmployees, *seed)
+		if err := WriteComparison(os.Stdout, line.Compare(items, Strategies(itemDurations))); err != nil {
+			fmt.Fprintf(os.Stderr, "輸出比較結果失敗: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	stats := line.Run(items)
+	stats.Print(os.Stdout)
+
+	if len(stats.Results) > 0 {
+		names := make([]string, len(stats.Results))
+		for i, r := range stats.Results {
+			names[i] = r.Item.String()
+		}
+		fmt.Printf("輸出順序: %s\n", strings.Join(names, ", "))
+	}
 
-	// 統計輸出
-	fmt.Println("\n========== 統計結果 ==========")
-	fmt.Printf("總處理時間: %v\n", totalTime)
-	totalProcessed := 0
-	for _, emp := range employees {
-		count := emp.GetCount()
-		fmt.Printf("員工 #%d 處理了 %d 件物品\n", emp.ID, count)
-		totalProcessed += count
+	if *timelineCSV != "" {
+		if err := writeFile(*timelineCSV, func(w io.Writer) error {
+			return WriteTimelineCSV(w, stats.Timeline, stats.Start)
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "輸出時間軸 CSV 失敗: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *timelineSVG != "" {
+		if err := writeFile(*timelineSVG, func(w io.Writer) error {
+			return WriteTimelineSVG(w, stats.Timeline, stats.Start)
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "輸出甘特圖失敗: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	report := NewReport(stats, *seed, flagConfig(flag.CommandLine))
+	if *reportJSON != "" {
+		if err := writeFile(*reportJSON, report.WriteJSON); err != nil {
+			fmt.Fprintf(os.Stderr, "輸出 JSON 報告失敗: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *reportMD != "" {
+		if err := writeFile(*reportMD, report.WriteMarkdown); err != nil {
+			fmt.Fprintf(os.Stderr, "輸出 Markdown 報告失敗: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if !report.Reconciled {
+		os.Exit(1)
+	}
+}
+
+// isTerminal 檔案是否為終端機
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// writeFile 創建檔案並以 write 寫入內容
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
 	}
-	fmt.Printf("總共處理: %d 件物品\n", totalProcessed)
+	return f.Close()
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket 令牌桶, 每秒補充 rate 個令牌, 最多累積 burst 個
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket 創建令牌桶, 一開始是滿的
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve 預約 n 個令牌, 回傳需要等待多久才能使用
+func (b *TokenBucket) reserve(n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	// 令牌可以預支, 後來的人排在後面等待
+	b.tokens -= float64(n)
+	if b.tokens >= 0 || b.rate <= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// WaitN 等待直到取得 n 個令牌, 回傳等待的時間
+func (b *TokenBucket) WaitN(n int) time.Duration {
+	d := b.reserve(n)
+	if d > 0 {
+		time.Sleep(d)
+	}
+	return d
+}
+
+// WithRateLimit 限制整條流水線每秒最多開始處理 rate 件物品
+func WithRateLimit(rate float64, burst int) Option {
+	return func(l *AssemblyLine) {
+		l.limiter = NewTokenBucket(rate, burst)
+	}
+}
+
+// WithTypeRateLimit 限制某類型物品每秒最多開始處理 rate 件
+func WithTypeRateLimit(kind string, rate float64, burst int) Option {
+	return func(l *AssemblyLine) {
+		if l.typeLimiters == nil {
+			l.typeLimiters = make(map[string]*TokenBucket)
+		}
+		l.typeLimiters[kind] = NewTokenBucket(rate, burst)
+	}
+}
+
+// throttle 開始處理前依全域及類型限流等待, 回傳等待的時間
+func (l *AssemblyLine) throttle(kind string, n int) time.Duration {
+	var waited time.Duration
+	if l.limiter != nil {
+		waited += l.limiter.WaitN(n)
+	}
+	if b, ok := l.typeLimiters[kind]; ok {
+		waited += b.WaitN(n)
+	}
+	return waited
+}
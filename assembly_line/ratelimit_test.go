@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTokenBucket_Reserve 驗證令牌用完後依補充速度排隊等待
+func TestTokenBucket_Reserve(t *testing.T) {
+	b := NewTokenBucket(100, 2)
+
+	if d := b.reserve(1); d != 0 {
+		t.Errorf("first reserve wait = %v, want 0", d)
+	}
+	if d := b.reserve(1); d != 0 {
+		t.Errorf("second reserve wait = %v, want 0 within burst", d)
+	}
+	// 每秒 100 個, 第三、四個令牌約需再等 10ms、20ms
+	if d := b.reserve(1); d < 9*time.Millisecond || d > 10*time.Millisecond {
+		t.Errorf("third reserve wait = %v, want about 10ms", d)
+	}
+	if d := b.reserve(1); d < 19*time.Millisecond || d > 20*time.Millisecond {
+		t.Errorf("fourth reserve wait = %v, want about 20ms", d)
+	}
+}
+
+// TestTokenBucket_Refill 驗證令牌補充不超過容量
+func TestTokenBucket_Refill(t *testing.T) {
+	b := NewTokenBucket(1000, 1)
+	b.reserve(1)
+	time.Sleep(20 * time.Millisecond)
+	if d := b.reserve(1); d != 0 {
+		t.Errorf("reserve after refill wait = %v, want 0", d)
+	}
+	if d := b.reserve(1); d == 0 {
+		t.Errorf("reserve beyond burst wait = 0, want > 0")
+	}
+}
+
+// TestAssemblyLine_TypeRateLimit 驗證只有受限類型需要等待, 等待時間與處理時間分開
+func TestAssemblyLine_TypeRateLimit(t *testing.T) {
+	line := NewAssemblyLine(2, WithTypeRateLimit("A", 50, 1))
+	stats := line.Run(newTestItems(4, time.Millisecond, "A", "B"))
+
+	if got := stats.TotalProcessed(); got != 8 {
+		t.Errorf("TotalProcessed = %d, want 8", got)
+	}
+	// 4 件 A 每秒 50 件, 至少要等 3 個 20ms 的間隔
+	if w := stats.Types["A"].Wait; w < 50*time.Millisecond {
+		t.Errorf("A wait = %v, want >= 50ms", w)
+	}
+	if w := stats.Types["B"].Wait; w != 0 {
+		t.Errorf("B wait = %v, want 0", w)
+	}
+
+	var waiting time.Duration
+	for _, e := range stats.Employees {
+		waiting += e.Waiting
+		if e.Busy > 20*time.Millisecond {
+			t.Errorf("employee #%d busy = %v, want rate limit wait excluded", e.ID, e.Busy)
+		}
+	}
+	if waiting != stats.Types["A"].Wait {
+		t.Errorf("employee waiting = %v, want %v", waiting, stats.Types["A"].Wait)
+	}
+}
+
+// TestAssemblyLine_GlobalRateLimit 驗證全域限流
+func TestAssemblyLine_GlobalRateLimit(t *testing.T) {
+	line := NewAssemblyLine(3, WithRateLimit(100, 1))
+	stats := line.Run(newTestItems(6, 0, "A"))
+
+	// 6 件物品每秒 100 件, 至少需要 50ms
+	if stats.TotalTime < 45*time.Millisecond {
+		t.Errorf("TotalTime = %v, want >= 45ms", stats.TotalTime)
+	}
+}
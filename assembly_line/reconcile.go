@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// credit 將一次提交標記為完成, 每次提交只計入一次, 同一件物品可以提交多次;
+// 已計入過 (例如重新分派後原本的員工才回報) 時回傳 false
+func (l *AssemblyLine) credit(s *submission) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s.credited {
+		l.rejected++
+		return false
+	}
+	s.credited = true
+	return true
+}
+
+// rejectCompletion 記錄不計入的完成回報
+func (l *AssemblyLine) rejectCompletion(e *Employee, item Item) {
+	fmt.Fprintf(l.out, "[%s] 員工 #%d 回報 %s 完成, 但已計入過, 不重複計算\n",
+		time.Now().Format(timeLayout), e.ID, item.String())
+}
+
+// Reconcile 核對完成數: 每次提交恰好計入一次完成或否決, 或停止時仍未完成;
+// 員工處理數的加總、各類型的處理數及結果數都與提交數一致.
+// 未完成的物品本身不算核對失敗
+func (s Stats) Reconcile() error {
+	var errs []error
+	processed := s.TotalProcessed()
+	if processed+s.Vetoed+s.Unfinished != s.Submitted {
+		errs = append(errs, fmt.Errorf("employees credited %d items, %d vetoed and %d unfinished, %d submitted",
+			processed, s.Vetoed, s.Unfinished, s.Submitted))
+	}
+	// 被拒絕的完成回報也做了處理, 仍計入類型統計
+	byType := 0
+	for _, t := range s.Types {
+		byType += t.Processed
+	}
+	if byType != processed+s.RejectedCompletions {
+		errs = append(errs, fmt.Errorf("types processed %d items, want %d credited + %d rejected",
+			byType, processed, s.RejectedCompletions))
+	}
+	results := len(s.Results) + s.DroppedResults
+	if results > 0 && results != processed+s.Vetoed {
+		errs = append(errs, fmt.Errorf("%d results for %d credited and %d vetoed items",
+			results, processed, s.Vetoed))
+	}
+	return errors.Join(errs...)
+}
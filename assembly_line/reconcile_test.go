@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// valueItem 以值比較的測試物品, 相同內容的兩件無法以物品本身區分
+type valueItem struct{ id int }
+
+func (i valueItem) Process()       {}
+func (i valueItem) String() string { return "value" }
+
+// recordingDispatcher 記錄所有放入的提交的派發器
+type recordingDispatcher struct {
+	Dispatcher
+	mu     sync.Mutex
+	pushed []Item
+}
+
+func (d *recordingDispatcher) Push(item Item) {
+	d.mu.Lock()
+	d.pushed = append(d.pushed, item)
+	d.mu.Unlock()
+	d.Dispatcher.Push(item)
+}
+
+// TestAssemblyLine_CompletionCountedOnce 驗證同一次提交重複回報完成時只計入一次
+func TestAssemblyLine_CompletionCountedOnce(t *testing.T) {
+	d := &recordingDispatcher{Dispatcher: NewFIFODispatcher(1)}
+	line := NewAssemblyLine(1, WithDispatcher(func([]*Employee) Dispatcher { return d }))
+	line.Start()
+	item := &testItem{kind: "once", id: 1}
+	if err := line.Submit(item); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	e := line.Employees()[0]
+	deadline := time.Now().Add(time.Second)
+	for e.GetCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 例如重新分派後原本的員工才回報完成
+	line.complete(e, d.pushed[0].(*submission), nil, nil, time.Now())
+	stats := line.Stop()
+
+	if e.GetCount() != 1 || stats.TotalProcessed() != 1 {
+		t.Errorf("GetCount() = %d, TotalProcessed() = %d, want 1", e.GetCount(), stats.TotalProcessed())
+	}
+	if stats.RejectedCompletions != 1 {
+		t.Errorf("RejectedCompletions = %d, want 1", stats.RejectedCompletions)
+	}
+	// 被拒絕的回報沒有實際處理, 類型統計只有一件
+	if err := stats.Reconcile(); err == nil || !strings.Contains(err.Error(), "types processed 1") {
+		t.Errorf("Reconcile() = %v, want a types mismatch", err)
+	}
+}
+
+// TestAssemblyLine_Reconcile 驗證重複提交相同的物品及故障重新分派後仍可核對
+func TestAssemblyLine_Reconcile(t *testing.T) {
+	items := []Item{valueItem{1}, valueItem{1}, valueItem{2}}
+	items = append(items, newTestItems(5, time.Millisecond, "a", "b")...)
+	line := NewAssemblyLine(2, WithResults(CompletionOrder),
+		WithFailures(Failure{AtAttempts: []int{1, 3}, Recovery: time.Millisecond}))
+	stats := line.Run(items)
+
+	if stats.Reassigned == 0 {
+		t.Error("Reassigned = 0, want crashes to requeue items")
+	}
+	if stats.TotalProcessed() != len(items) || stats.RejectedCompletions != 0 {
+		t.Errorf("TotalProcessed() = %d, RejectedCompletions = %d, want %d and 0",
+			stats.TotalProcessed(), stats.RejectedCompletions, len(items))
+	}
+	if err := stats.Reconcile(); err != nil {
+		t.Errorf("Reconcile() = %v, want nil", err)
+	}
+	if r := NewReport(stats, 0, nil); !r.Reconciled || r.ReconcileError != "" {
+		t.Errorf("report Reconciled = %v (%q), want true", r.Reconciled, r.ReconcileError)
+	}
+}
+
+// TestAssemblyLine_ReconcileUnfinished 驗證員工全部故障後剩下的物品計為未完成, 核對仍然一致
+func TestAssemblyLine_ReconcileUnfinished(t *testing.T) {
+	items := newTestItems(4, time.Millisecond, "a")
+	line := NewAssemblyLine(1, WithFailures(Failure{AtAttempts: []int{3}}))
+	stats := line.Run(items)
+
+	if stats.TotalProcessed() != 2 || stats.Unfinished != 2 {
+		t.Errorf("TotalProcessed() = %d, Unfinished = %d, want 2 and 2", stats.TotalProcessed(), stats.Unfinished)
+	}
+	if err := stats.Reconcile(); err != nil {
+		t.Errorf("Reconcile() = %v, want nil", err)
+	}
+	if r := NewReport(stats, 0, nil); !r.Reconciled || r.Unfinished != 2 {
+		t.Errorf("report Reconciled = %v, Unfinished = %d, want true and 2", r.Reconciled, r.Unfinished)
+	}
+}
+
+// TestStats_ReconcileMismatch 驗證核對失敗時說明每項差異
+func TestStats_ReconcileMismatch(t *testing.T) {
+	stats := Stats{
+		Submitted: 3,
+		Employees: []EmployeeStats{{ID: 1, Processed: 1}, {ID: 2, Processed: 1}},
+		Types:     map[string]TypeStats{"a": {Processed: 3}},
+		Results:   []Result{{}},
+	}
+	err := stats.Reconcile()
+	if err == nil {
+		t.Fatal("Reconcile() = nil, want error")
+	}
+	for _, want := range []string{"credited 2 items, 0 vetoed and 0 unfinished, 3 submitted", "types processed 3", "1 results for 2 credited"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Reconcile() = %v, want it to mention %q", err, want)
+		}
+	}
+	if r := NewReport(stats, 0, nil); r.Reconciled || r.ReconcileError == "" {
+		t.Errorf("report Reconciled = %v, want false with an error", r.Reconciled)
+	}
+}
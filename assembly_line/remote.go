@@ -0,0 +1,494 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/rpc"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// leasePollWait 遠端員工租用物品時, 佇列為空最多等待多久才回覆
+const leasePollWait = time.Second
+
+// remoteJob 協調者佇列中的物品信封, 只在協調者內部排隊, 不會在本地處理
+type remoteJob struct {
+	env Envelope
+}
+
+func (j *remoteJob) Process() {}
+
+func (j *remoteJob) String() string {
+	return fmt.Sprintf("%s #%d", j.env.Type, j.env.ID)
+}
+
+// lease 遠端員工租用中的物品
+type lease struct {
+	worker  string
+	job     *remoteJob
+	expires time.Time
+}
+
+// CoordinatorStats 協調者的統計
+type CoordinatorStats struct {
+	Submitted int
+	Completed int
+	// Failed 物品處理時回傳錯誤的數量, 同樣算完成
+	Failed int
+	// Reassigned 租約到期而重新排入的次數
+	Reassigned int
+	// Stale 租約到期後才回報完成而被拒絕的次數
+	Stale int
+	// Workers 每位遠端員工完成的物品數
+	Workers map[string]int
+}
+
+// Print 打印協調者的統計
+func (s CoordinatorStats) Print(w io.Writer) {
+	fmt.Fprintln(w, "\n========== 統計結果 ==========")
+	fmt.Fprintf(w, "提交: %d 件, 完成: %d 件 (失敗 %d 件)\n", s.Submitted, s.Completed, s.Failed)
+	if s.Reassigned > 0 || s.Stale > 0 {
+		fmt.Fprintf(w, "租約到期重新分派: %d 次, 逾時回報被拒: %d 次\n", s.Reassigned, s.Stale)
+	}
+	names := make([]string, 0, len(s.Workers))
+	for name := range s.Workers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "遠端員工 %s 處理了 %d 件物品\n", name, s.Workers[name])
+	}
+}
+
+// Coordinator 持有物品佇列, 遠端員工透過 TCP 租用物品、回報心跳及完成;
+// 租約到期未續約時物品重新排入給其他員工
+type Coordinator struct {
+	registry *ItemRegistry
+	ttl      time.Duration
+	out      io.Writer
+	// now 為時鐘, 測試時可替換
+	now func() time.Time
+
+	// queue 依優先順序排列, 同優先順序先進先出
+	queue *queueDispatcher
+	// pending 尚未完成的物品數, 在 Close 之前多保留一件, 歸零時關閉佇列
+	pending int64
+	done    chan struct{}
+
+	mu        sync.Mutex
+	leases    map[uint64]*lease
+	nextLease uint64
+	closed    bool
+	stats     CoordinatorStats
+}
+
+// NewCoordinator 創建協調者, 遠端員工需在 leaseTTL 內回報心跳; leaseTTL 必須大於 0
+func NewCoordinator(registry *ItemRegistry, leaseTTL time.Duration, out io.Writer) (*Coordinator, error) {
+	if leaseTTL <= 0 {
+		return nil, fmt.Errorf("lease TTL %v must be positive", leaseTTL)
+	}
+	return newCoordinator(registry, leaseTTL, out, time.Now), nil
+}
+
+func newCoordinator(registry *ItemRegistry, leaseTTL time.Duration, out io.Writer, now func() time.Time) *Coordinator {
+	c := &Coordinator{
+		registry: registry,
+		ttl:      leaseTTL,
+		out:      out,
+		now:      now,
+		queue: newQueueDispatcher(func(items []Item, e *Employee) int {
+			best := 0
+			for i, item := range items {
+				if item.(*remoteJob).env.Priority > items[best].(*remoteJob).env.Priority {
+					best = i
+				}
+			}
+			return best
+		}),
+		pending: 1,
+		done:    make(chan struct{}),
+		leases:  make(map[uint64]*lease),
+		stats:   CoordinatorStats{Workers: make(map[string]int)},
+	}
+	go c.expireLeases()
+	return c
+}
+
+// Submit 將物品裝入信封後排入佇列
+func (c *Coordinator) Submit(item Item) error {
+	env, err := c.registry.Wrap(item)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrStopped
+	}
+	c.stats.Submitted++
+	atomic.AddInt64(&c.pending, 1)
+	c.mu.Unlock()
+
+	c.queue.Push(&remoteJob{env: env})
+	return nil
+}
+
+// Close 不再接受新物品, 所有物品完成後遠端員工會收到已清空的回覆
+func (c *Coordinator) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	c.mu.Unlock()
+	c.finish()
+}
+
+// finish 標記一件物品完成, 全部完成時關閉佇列
+func (c *Coordinator) finish() {
+	if atomic.AddInt64(&c.pending, -1) == 0 {
+		c.queue.Close()
+		close(c.done)
+	}
+}
+
+// Wait 等待 Close 之前提交的物品全部完成, 回傳統計
+func (c *Coordinator) Wait() CoordinatorStats {
+	<-c.done
+	return c.Stats()
+}
+
+// Stats 目前的統計
+func (c *Coordinator) Stats() CoordinatorStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Workers = make(map[string]int, len(c.stats.Workers))
+	for name, n := range c.stats.Workers {
+		s.Workers[name] = n
+	}
+	return s
+}
+
+// Serve 接受遠端員工的連線, 直到 l 關閉時回傳 nil
+func (c *Coordinator) Serve(l net.Listener) error {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("Coordinator", &coordinatorService{c: c}); err != nil {
+		return err
+	}
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go srv.ServeConn(conn)
+	}
+}
+
+// expireLeases 定期收回到期的租約
+func (c *Coordinator) expireLeases() {
+	ticker := time.NewTicker(c.ttl / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.expire(c.now())
+		}
+	}
+}
+
+// expire 收回在 now 之前到期的租約, 將物品重新排入
+func (c *Coordinator) expire(now time.Time) {
+	var expired []*lease
+	c.mu.Lock()
+	for id, l := range c.leases {
+		if now.After(l.expires) {
+			delete(c.leases, id)
+			expired = append(expired, l)
+			c.stats.Reassigned++
+		}
+	}
+	c.mu.Unlock()
+	for _, l := range expired {
+		fmt.Fprintf(c.out, "[%s] 遠端員工 %s 的租約到期, %s 重新排入\n",
+			time.Now().Format(timeLayout), l.worker, l.job.String())
+		c.queue.Push(l.job)
+	}
+}
+
+// LeaseArgs 租用物品的請求
+type LeaseArgs struct {
+	Worker string
+}
+
+// LeaseReply 租用物品的回覆, OK 為 false 時表示暫時沒有物品, Drained 表示已全部完成
+type LeaseReply struct {
+	OK       bool
+	Drained  bool
+	LeaseID  uint64
+	Envelope Envelope
+	TTL      time.Duration
+}
+
+// HeartbeatArgs 續約的請求
+type HeartbeatArgs struct {
+	Worker  string
+	LeaseID uint64
+}
+
+// HeartbeatReply 續約的回覆, OK 為 false 表示租約已到期, 物品已交給其他員工
+type HeartbeatReply struct {
+	OK bool
+}
+
+// CompleteArgs 回報完成的請求, Err 為物品處理回傳的錯誤
+type CompleteArgs struct {
+	Worker  string
+	LeaseID uint64
+	Err     string
+}
+
+// CompleteReply 回報完成的回覆, Accepted 為 false 表示租約已到期, 這次完成不算數
+type CompleteReply struct {
+	Accepted bool
+}
+
+// coordinatorService 提供給遠端員工的 RPC 方法
+type coordinatorService struct {
+	c *Coordinator
+}
+
+func (s *coordinatorService) Lease(args LeaseArgs, reply *LeaseReply) error {
+	c := s.c
+	ctx, cancel := context.WithTimeout(context.Background(), leasePollWait)
+	defer cancel()
+	item, err := c.queue.Next(ctx, nil)
+	if errors.Is(err, ErrDrained) {
+		reply.Drained = true
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+
+	job := item.(*remoteJob)
+	job.env.Attempts++
+	c.mu.Lock()
+	c.nextLease++
+	id := c.nextLease
+	c.leases[id] = &lease{worker: args.Worker, job: job, expires: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	fmt.Fprintf(c.out, "[%s] 遠端員工 %s 租用 %s\n", time.Now().Format(timeLayout), args.Worker, job.String())
+	*reply = LeaseReply{OK: true, LeaseID: id, Envelope: job.env, TTL: c.ttl}
+	return nil
+}
+
+func (s *coordinatorService) Heartbeat(args HeartbeatArgs, reply *HeartbeatReply) error {
+	c := s.c
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if l, ok := c.leases[args.LeaseID]; ok && l.worker == args.Worker {
+		l.expires = c.now().Add(c.ttl)
+		reply.OK = true
+	}
+	return nil
+}
+
+func (s *coordinatorService) Complete(args CompleteArgs, reply *CompleteReply) error {
+	c := s.c
+	c.mu.Lock()
+	l, ok := c.leases[args.LeaseID]
+	if !ok || l.worker != args.Worker {
+		c.stats.Stale++
+		c.mu.Unlock()
+		return nil
+	}
+	delete(c.leases, args.LeaseID)
+	c.stats.Completed++
+	if args.Err != "" {
+		c.stats.Failed++
+	}
+	c.stats.Workers[args.Worker]++
+	c.mu.Unlock()
+
+	fmt.Fprintf(c.out, "[%s] 遠端員工 %s 完成 %s\n", time.Now().Format(timeLayout), args.Worker, l.job.String())
+	reply.Accepted = true
+	c.finish()
+	return nil
+}
+
+// RemoteWorker 連線到協調者的遠端員工
+type RemoteWorker struct {
+	ID       string
+	Registry *ItemRegistry
+	// Heartbeat 回報心跳的間隔, 應小於協調者的租約時間
+	Heartbeat time.Duration
+	Out       io.Writer
+}
+
+// Run 連線到 addr 的協調者, 不斷租用物品處理直到全部完成, 回傳處理並被接受的物品數
+func (w *RemoteWorker) Run(addr string) (int, error) {
+	if w.Heartbeat <= 0 {
+		return 0, fmt.Errorf("heartbeat interval %v must be positive", w.Heartbeat)
+	}
+	client, err := rpc.Dial("tcp", addr)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	out := w.Out
+	if out == nil {
+		out = io.Discard
+	}
+	processed := 0
+	for {
+		var lr LeaseReply
+		if err := client.Call("Coordinator.Lease", LeaseArgs{Worker: w.ID}, &lr); err != nil {
+			return processed, err
+		}
+		if lr.Drained {
+			return processed, nil
+		}
+		if !lr.OK {
+			continue
+		}
+
+		errText := ""
+		item, err := w.Registry.Unwrap(lr.Envelope)
+		if err != nil {
+			// 無法還原的物品重試也沒用, 直接回報失敗
+			errText = err.Error()
+		} else {
+			errText = w.process(client, lr.LeaseID, item, out)
+		}
+
+		var cr CompleteReply
+		args := CompleteArgs{Worker: w.ID, LeaseID: lr.LeaseID, Err: errText}
+		if err := client.Call("Coordinator.Complete", args, &cr); err != nil {
+			return processed, err
+		}
+		if cr.Accepted {
+			processed++
+		} else {
+			fmt.Fprintf(out, "[%s] 遠端員工 %s 的 %s 租約已到期, 完成不算數\n",
+				time.Now().Format(timeLayout), w.ID, lr.Envelope.Type)
+		}
+	}
+}
+
+// process 處理物品並定期續約, 回傳物品的錯誤訊息
+func (w *RemoteWorker) process(client *rpc.Client, leaseID uint64, item Item, out io.Writer) string {
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(w.Heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				var hr HeartbeatReply
+				client.Call("Coordinator.Heartbeat", HeartbeatArgs{Worker: w.ID, LeaseID: leaseID}, &hr)
+			}
+		}
+	}()
+
+	start := time.Now()
+	fmt.Fprintf(out, "[%s] 遠端員工 %s 開始處理 %s\n", start.Format(timeLayout), w.ID, item.String())
+	_, err := processWithSpeed(item, 1)
+	end := time.Now()
+	fmt.Fprintf(out, "[%s] 遠端員工 %s 完成處理 %s (耗時: %v)\n", end.Format(timeLayout), w.ID, item.String(), end.Sub(start))
+	close(stop)
+	wg.Wait()
+
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// runCoordinator 實作 coordinator 子指令: 提交物品並等待遠端員工處理完畢
+func runCoordinator(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("coordinator", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("listen", "127.0.0.1:7070", "監聽的位址")
+	n := fs.Int("items", 10, "每種物品的數量")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "隨機種子, 用於打亂物品順序")
+	ttl := fs.Duration("lease", 2*time.Second, "租約時間, 遠端員工需在期限內回報心跳")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	c, err := NewCoordinator(BuiltinItems(), *ttl, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "-lease: %v\n", err)
+		return 2
+	}
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintf(stderr, "監聽失敗: %v\n", err)
+		return 1
+	}
+	defer l.Close()
+
+	items := newItems(*n)
+	r := rand.New(rand.NewSource(*seed))
+	r.Shuffle(len(items), func(i, j int) {
+		items[i], items[j] = items[j], items[i]
+	})
+	for _, item := range items {
+		if err := c.Submit(item); err != nil {
+			fmt.Fprintf(stderr, "提交失敗: %v\n", err)
+			return 1
+		}
+	}
+	c.Close()
+
+	fmt.Fprintf(stdout, "協調者在 %s 等待遠端員工\n", l.Addr())
+	go c.Serve(l)
+	c.Wait().Print(stdout)
+	// 讓遠端員工收到已清空的回覆後再關閉
+	time.Sleep(leasePollWait)
+	return 0
+}
+
+// runWorker 實作 worker 子指令: 連線到協調者處理物品
+func runWorker(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("connect", "127.0.0.1:7070", "協調者的位址")
+	host, _ := os.Hostname()
+	id := fs.String("id", fmt.Sprintf("%s-%d", host, os.Getpid()), "遠端員工名稱")
+	heartbeat := fs.Duration("heartbeat", 500*time.Millisecond, "回報心跳的間隔")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	w := &RemoteWorker{ID: *id, Registry: BuiltinItems(), Heartbeat: *heartbeat, Out: stdout}
+	n, err := w.Run(*addr)
+	fmt.Fprintf(stdout, "遠端員工 %s 處理了 %d 件物品\n", *id, n)
+	if err != nil {
+		fmt.Fprintf(stderr, "遠端員工中斷: %v\n", err)
+		return 1
+	}
+	return 0
+}
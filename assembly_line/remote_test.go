@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// remoteRegistry 註冊 testItem 的物品類型, 還原的物品處理時累加 processed
+func remoteRegistry(processed *int32) *ItemRegistry {
+	r := NewItemRegistry()
+	r.Register(ItemType{
+		Name: "task",
+		Encode: func(item Item) (int, json.RawMessage, error) {
+			i := item.(*testItem)
+			payload, err := json.Marshal(i.d)
+			return i.id, payload, err
+		},
+		Decode: func(id int, payload json.RawMessage) (Item, error) {
+			var d time.Duration
+			if err := json.Unmarshal(payload, &d); err != nil {
+				return nil, err
+			}
+			return &testItem{kind: "task", id: id, d: d, processed: processed}, nil
+		},
+	})
+	return r
+}
+
+// startCoordinator 在 loopback 上啟動使用測試時鐘的協調者並提交 n 件耗時 d 的物品,
+// 時鐘不前進時租約不會到期
+func startCoordinator(t *testing.T, registry *ItemRegistry, clock *fakeClock, ttl time.Duration, n int, d time.Duration) (*Coordinator, string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	c := newCoordinator(registry, ttl, io.Discard, clock.Now)
+	for _, item := range newTestItems(n, d, "task") {
+		if err := c.Submit(item); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+	c.Close()
+	go c.Serve(l)
+	return c, l.Addr().String()
+}
+
+// runWorkers 啟動多位遠端員工並等待全部結束, 回傳各自接受的完成數
+func runWorkers(t *testing.T, addr string, registry *ItemRegistry, ids ...string) map[string]int {
+	t.Helper()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	counts := make(map[string]int)
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			w := &RemoteWorker{ID: id, Registry: registry, Heartbeat: 20 * time.Millisecond}
+			n, err := w.Run(addr)
+			if err != nil {
+				t.Errorf("worker %s Run() error = %v", id, err)
+			}
+			mu.Lock()
+			counts[id] = n
+			mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return counts
+}
+
+// TestCoordinator_RemoteWorkers 驗證多位遠端員工分工處理全部物品
+func TestCoordinator_RemoteWorkers(t *testing.T) {
+	var processed int32
+	registry := remoteRegistry(&processed)
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}
+	c, addr := startCoordinator(t, registry, clock, time.Minute, 6, 20*time.Millisecond)
+
+	counts := runWorkers(t, addr, registry, "a", "b", "c")
+	stats := c.Wait()
+
+	if stats.Submitted != 6 || stats.Completed != 6 || stats.Failed != 0 {
+		t.Errorf("stats = %+v, want 6 submitted and completed", stats)
+	}
+	if stats.Reassigned != 0 || stats.Stale != 0 {
+		t.Errorf("Reassigned = %d, Stale = %d, want 0", stats.Reassigned, stats.Stale)
+	}
+	if got := atomic.LoadInt32(&processed); got != 6 {
+		t.Errorf("processed = %d, want 6", got)
+	}
+	total := 0
+	for id, n := range counts {
+		if stats.Workers[id] != n {
+			t.Errorf("Workers[%s] = %d, worker reported %d", id, stats.Workers[id], n)
+		}
+		total += n
+	}
+	if total != 6 {
+		t.Errorf("workers completed %d items, want 6", total)
+	}
+}
+
+// TestCoordinator_ReassignsExpiredLease 驗證員工消失後租約到期, 物品交給其他員工,
+// 且到期後才回報的完成不算數
+func TestCoordinator_ReassignsExpiredLease(t *testing.T) {
+	var processed int32
+	registry := remoteRegistry(&processed)
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}
+	ttl := time.Minute
+	c, addr := startCoordinator(t, registry, clock, ttl, 4, 10*time.Millisecond)
+
+	// 租用一件物品後不再回報心跳
+	client, err := rpc.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Close()
+	var lr LeaseReply
+	if err := client.Call("Coordinator.Lease", LeaseArgs{Worker: "gone"}, &lr); err != nil || !lr.OK {
+		t.Fatalf("Lease() = %+v, %v, want a lease", lr, err)
+	}
+	if lr.Envelope.Attempts != 1 || lr.TTL != ttl {
+		t.Errorf("Lease() = %+v, want attempt 1 with ttl %v", lr, ttl)
+	}
+	clock.Advance(2 * ttl)
+	c.expire(clock.Now())
+
+	var hr HeartbeatReply
+	client.Call("Coordinator.Heartbeat", HeartbeatArgs{Worker: "gone", LeaseID: lr.LeaseID}, &hr)
+	if hr.OK {
+		t.Error("Heartbeat() after expiry OK = true, want false")
+	}
+	var cr CompleteReply
+	client.Call("Coordinator.Complete", CompleteArgs{Worker: "gone", LeaseID: lr.LeaseID}, &cr)
+	if cr.Accepted {
+		t.Error("Complete() after expiry Accepted = true, want false")
+	}
+
+	counts := runWorkers(t, addr, registry, "a")
+	stats := c.Wait()
+
+	if counts["a"] != 4 || stats.Completed != 4 {
+		t.Errorf("worker a completed %d, stats = %+v, want all 4", counts["a"], stats)
+	}
+	if stats.Reassigned != 1 || stats.Stale != 1 {
+		t.Errorf("Reassigned = %d, Stale = %d, want 1 each", stats.Reassigned, stats.Stale)
+	}
+	if _, ok := stats.Workers["gone"]; ok {
+		t.Errorf("Workers = %v, want no completions for the vanished worker", stats.Workers)
+	}
+}
+
+// TestCoordinator_HeartbeatRenewsLease 驗證心跳延長租約, 停止心跳後才到期
+func TestCoordinator_HeartbeatRenewsLease(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}
+	c := newCoordinator(remoteRegistry(nil), time.Minute, io.Discard, clock.Now)
+	c.Submit(&testItem{kind: "task", id: 1})
+	c.Close()
+
+	svc := &coordinatorService{c: c}
+	var lr LeaseReply
+	svc.Lease(LeaseArgs{Worker: "a"}, &lr)
+	clock.Advance(45 * time.Second)
+	var hr HeartbeatReply
+	if svc.Heartbeat(HeartbeatArgs{Worker: "a", LeaseID: lr.LeaseID}, &hr); !hr.OK {
+		t.Fatal("Heartbeat() OK = false, want true before expiry")
+	}
+
+	// 已超過原本的期限, 但續約後仍有效
+	clock.Advance(45 * time.Second)
+	c.expire(clock.Now())
+	if got := c.Stats().Reassigned; got != 0 {
+		t.Errorf("Reassigned = %d after renewal, want 0", got)
+	}
+	clock.Advance(time.Minute)
+	c.expire(clock.Now())
+	if got := c.Stats().Reassigned; got != 1 {
+		t.Errorf("Reassigned = %d without heartbeats, want 1", got)
+	}
+}
+
+// TestCoordinator_PriorityAndClose 驗證高優先順序的物品先被租用, 且 Close 後不再接受物品
+func TestCoordinator_PriorityAndClose(t *testing.T) {
+	registry := BuiltinItems()
+	registry.Register(ItemType{
+		Name:   "urgent",
+		Encode: func(item Item) (int, json.RawMessage, error) { return item.(*priorityItem).id, nil, nil },
+		Decode: func(id int, _ json.RawMessage) (Item, error) { return &testItem{kind: "urgent", id: id}, nil },
+	})
+	c, err := NewCoordinator(registry, time.Second, io.Discard)
+	if err != nil {
+		t.Fatalf("NewCoordinator() error = %v", err)
+	}
+	c.Submit(&Item1{ID: 1})
+	c.Submit(&priorityItem{testItem: testItem{kind: "urgent", id: 2}, priority: 5})
+	c.Close()
+	if err := c.Submit(&Item1{ID: 3}); err != ErrStopped {
+		t.Errorf("Submit() after Close error = %v, want ErrStopped", err)
+	}
+
+	svc := &coordinatorService{c: c}
+	var lr LeaseReply
+	svc.Lease(LeaseArgs{Worker: "a"}, &lr)
+	if lr.Envelope.Type != "urgent" {
+		t.Errorf("first lease = %s, want urgent", lr.Envelope.Type)
+	}
+}
+
+// TestCoordinator_InvalidTTL 驗證租約時間及心跳間隔必須大於 0
+func TestCoordinator_InvalidTTL(t *testing.T) {
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		if _, err := NewCoordinator(BuiltinItems(), ttl, io.Discard); err == nil {
+			t.Errorf("NewCoordinator(%v) error = nil, want an error", ttl)
+		}
+	}
+	if code := runCoordinator([]string{"-lease", "0", "-listen", "127.0.0.1:0"}, io.Discard, io.Discard); code != 2 {
+		t.Errorf("runCoordinator(-lease 0) = %d, want 2", code)
+	}
+	w := &RemoteWorker{ID: "a", Registry: BuiltinItems()}
+	if _, err := w.Run("127.0.0.1:0"); err == nil {
+		t.Error("Run() with zero Heartbeat error = nil, want an error")
+	}
+}
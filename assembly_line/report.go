@@ -0,0 +1,387 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report 可保存及比較的執行報告, 時間欄位皆為毫秒
+type Report struct {
+	Seed      int64             `json:"seed"`
+	Config    map[string]string `json:"config"`
+	Start     time.Time         `json:"start"`
+	TotalMs   float64           `json:"total_ms"`
+	Submitted int               `json:"submitted"`
+	Processed int               `json:"processed"`
+
+	Employees []EmployeeReport `json:"employees"`
+	Types     []TypeReport     `json:"types"`
+	Classes   []ClassReport    `json:"classes,omitempty"`
+	Failures  FailureReport    `json:"failures"`
+
+	Changeovers      int     `json:"changeovers,omitempty"`
+	ChangeoverMs     float64 `json:"changeover_ms,omitempty"`
+	FIFOChangeoverMs float64 `json:"fifo_changeover_ms,omitempty"`
+
+	Delayed       int     `json:"delayed,omitempty"`
+	Late          int     `json:"late,omitempty"`
+	LatenessMs    float64 `json:"lateness_ms,omitempty"`
+	MaxLatenessMs float64 `json:"max_lateness_ms,omitempty"`
+
+	Duplicates int `json:"duplicates,omitempty"`
+
+	RejectedCompletions int `json:"rejected_completions,omitempty"`
+	Vetoed              int `json:"vetoed,omitempty"`
+	Unfinished          int `json:"unfinished,omitempty"`
+	// Reconciled 每件提交的物品是否恰好計入一次完成、否決或未完成, 否則 ReconcileError 說明差異
+	Reconciled     bool   `json:"reconciled"`
+	ReconcileError string `json:"reconcile_error,omitempty"`
+}
+
+// EmployeeReport 單一員工的報告
+type EmployeeReport struct {
+	ID            int     `json:"id"`
+	Processed     int     `json:"processed"`
+	BusyMs        float64 `json:"busy_ms"`
+	IdleMs        float64 `json:"idle_ms"`
+	UnavailableMs float64 `json:"unavailable_ms"`
+	WaitingMs     float64 `json:"waiting_ms"`
+	BlockedMs     float64 `json:"blocked_ms"`
+	Crashes       int     `json:"crashes"`
+}
+
+// TypeReport 單一物品類型的報告
+type TypeReport struct {
+	Type      string  `json:"type"`
+	Processed int     `json:"processed"`
+	BusyMs    float64 `json:"busy_ms"`
+	AvgMs     float64 `json:"avg_ms"`
+	WaitMs    float64 `json:"wait_ms"`
+}
+
+// ClassReport 公平分配時單一分組的報告
+type ClassReport struct {
+	Class      string  `json:"class"`
+	Weight     float64 `json:"weight"`
+	Processed  int     `json:"processed"`
+	BusyMs     float64 `json:"busy_ms"`
+	Throughput float64 `json:"throughput"`
+}
+
+// FailureReport 故障相關的報告
+type FailureReport struct {
+	Crashes    int     `json:"crashes"`
+	Reassigned int     `json:"reassigned"`
+	LostMs     float64 `json:"lost_ms"`
+}
+
+// ms 轉成毫秒, 保留三位小數
+func ms(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Microsecond)) / 1000
+}
+
+// NewReport 由統計結果產生報告
+func NewReport(s Stats, seed int64, config map[string]string) Report {
+	r := Report{
+		Seed:             seed,
+		Config:           config,
+		Start:            s.Start,
+		TotalMs:          ms(s.TotalTime),
+		Submitted:        s.Submitted,
+		Processed:        s.TotalProcessed(),
+		Changeovers:      s.Changeovers,
+		ChangeoverMs:     ms(s.ChangeoverTime),
+		FIFOChangeoverMs: ms(s.FIFOChangeoverTime),
+		Delayed:          s.Delayed,
+		Late:             s.Late,
+		LatenessMs:       ms(s.Lateness),
+		MaxLatenessMs:    ms(s.MaxLateness),
+		Duplicates:       s.Duplicates,
+		Failures: FailureReport{
+			Crashes:    s.Crashes,
+			Reassigned: s.Reassigned,
+			LostMs:     ms(s.LostTime),
+		},
+		RejectedCompletions: s.RejectedCompletions,
+		Vetoed:              s.Vetoed,
+		Unfinished:          s.Unfinished,
+		Reconciled:          true,
+	}
+	if err := s.Reconcile(); err != nil {
+		r.Reconciled = false
+		r.ReconcileError = err.Error()
+	}
+	for _, e := range s.Employees {
+		r.Employees = append(r.Employees, EmployeeReport{
+			ID:            e.ID,
+			Processed:     e.Processed,
+			BusyMs:        ms(e.Busy),
+			IdleMs:        ms(e.Idle),
+			UnavailableMs: ms(e.Unavailable),
+			WaitingMs:     ms(e.Waiting),
+			BlockedMs:     ms(e.Blocked),
+			Crashes:       e.Crashes,
+		})
+	}
+	for _, kind := range s.typeNames() {
+		ts := s.Types[kind]
+		r.Types = append(r.Types, TypeReport{
+			Type:      kind,
+			Processed: ts.Processed,
+			BusyMs:    ms(ts.Busy),
+			AvgMs:     ms(ts.Avg()),
+			WaitMs:    ms(ts.Wait),
+		})
+	}
+	for _, class := range s.classNames() {
+		cs := s.Classes[class]
+		r.Classes = append(r.Classes, ClassReport{
+			Class:      class,
+			Weight:     cs.Weight,
+			Processed:  cs.Processed,
+			BusyMs:     ms(cs.Busy),
+			Throughput: math.Round(cs.Throughput(s.TotalTime)*1000) / 1000,
+		})
+	}
+	return r
+}
+
+// WriteJSON 以縮排的 JSON 輸出報告
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadReport 讀取 JSON 報告
+func ReadReport(rd io.Reader) (Report, error) {
+	var r Report
+	err := json.NewDecoder(rd).Decode(&r)
+	return r, err
+}
+
+// WriteMarkdown 輸出適合貼在 PR 中的 Markdown 報告
+func (r Report) WriteMarkdown(w io.Writer) error {
+	ew := &errWriter{w: w}
+	ew.printf("## 流水線執行報告\n\n")
+	ew.printf("| 項目 | 數值 |\n|---|---|\n")
+	ew.printf("| 總處理時間 | %.3f ms |\n", r.TotalMs)
+	ew.printf("| 提交 / 處理 | %d / %d |\n", r.Submitted, r.Processed)
+	ew.printf("| Seed | %d |\n", r.Seed)
+	if r.Changeovers > 0 || r.FIFOChangeoverMs > 0 {
+		ew.printf("| 切換類型 | %d 次, %.3f ms (先進先出估計 %.3f ms) |\n",
+			r.Changeovers, r.ChangeoverMs, r.FIFOChangeoverMs)
+	}
+	if r.Delayed > 0 {
+		ew.printf("| 排程物品 | %d 件, 延誤 %d 件, 共 %.3f ms (最長 %.3f ms) |\n",
+			r.Delayed, r.Late, r.LatenessMs, r.MaxLatenessMs)
+	}
+	if r.Duplicates > 0 {
+		ew.printf("| 重複提交略過 | %d 件 |\n", r.Duplicates)
+	}
+	if r.Vetoed > 0 {
+		ew.printf("| 否決 | %d 件 |\n", r.Vetoed)
+	}
+	if r.Unfinished > 0 {
+		ew.printf("| 未完成 | %d 件 |\n", r.Unfinished)
+	}
+	if r.RejectedCompletions > 0 {
+		ew.printf("| 重複回報完成 | %d 次, 未重複計算 |\n", r.RejectedCompletions)
+	}
+	if r.Reconciled {
+		ew.printf("| 核對 | 一致 |\n")
+	} else {
+		ew.printf("| 核對 | 失敗: %s |\n", strings.ReplaceAll(r.ReconcileError, "\n", "; "))
+	}
+	if r.Failures.Crashes > 0 {
+		ew.printf("| 故障 | %d 次, 重新分派 %d 件, 損失 %.3f ms |\n",
+			r.Failures.Crashes, r.Failures.Reassigned, r.Failures.LostMs)
+	}
+
+	if len(r.Config) > 0 {
+		keys := make([]string, 0, len(r.Config))
+		for k := range r.Config {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		ew.printf("\n### 設定\n\n| 參數 | 值 |\n|---|---|\n")
+		for _, k := range keys {
+			ew.printf("| `%s` | `%s` |\n", k, markdownEscape(r.Config[k]))
+		}
+	}
+
+	ew.printf("\n### 員工\n\n| 員工 | 處理 | 忙碌 (ms) | 閒置 (ms) | 不在班 (ms) | 限流等待 (ms) | 阻擋 (ms) | 故障 |\n|---|---|---|---|---|---|---|---|\n")
+	for _, e := range r.Employees {
+		ew.printf("| #%d | %d | %.3f | %.3f | %.3f | %.3f | %.3f | %d |\n",
+			e.ID, e.Processed, e.BusyMs, e.IdleMs, e.UnavailableMs, e.WaitingMs, e.BlockedMs, e.Crashes)
+	}
+
+	ew.printf("\n### 物品類型\n\n| 類型 | 處理 | 平均 (ms) | 總處理 (ms) | 限流等待 (ms) |\n|---|---|---|---|---|\n")
+	for _, t := range r.Types {
+		ew.printf("| %s | %d | %.3f | %.3f | %.3f |\n", markdownEscape(t.Type), t.Processed, t.AvgMs, t.BusyMs, t.WaitMs)
+	}
+
+	if len(r.Classes) > 0 {
+		ew.printf("\n### 公平分配\n\n| 分組 | 權重 | 處理 | 佔用 (ms) | 吞吐量 (件/秒) |\n|---|---|---|---|---|\n")
+		for _, c := range r.Classes {
+			ew.printf("| %s | %g | %d | %.3f | %.3f |\n", markdownEscape(c.Class), c.Weight, c.Processed, c.BusyMs, c.Throughput)
+		}
+	}
+	return ew.err
+}
+
+// ReportDiff 兩份報告中同一個指標的差異
+type ReportDiff struct {
+	Metric string
+	Old    float64
+	New    float64
+	// HigherIsBetter 數值變大是改善 (例如處理數量)
+	HigherIsBetter bool
+	// Info 僅供參考, 不判斷是否退步 (例如各員工的分配)
+	Info bool
+}
+
+// Delta 變化量
+func (d ReportDiff) Delta() float64 {
+	return d.New - d.Old
+}
+
+// Percent 相對舊值的變化百分比, 舊值為 0 時回傳 0
+func (d ReportDiff) Percent() float64 {
+	if d.Old == 0 {
+		return 0
+	}
+	return d.Delta() / d.Old * 100
+}
+
+// Regressed 變差的幅度是否超過 threshold 百分比
+func (d ReportDiff) Regressed(threshold float64) bool {
+	if d.Info {
+		return false
+	}
+	worse := d.Delta() > 0
+	if d.HigherIsBetter {
+		worse = d.Delta() < 0
+	}
+	if !worse {
+		return false
+	}
+	if d.Old == 0 {
+		return true
+	}
+	return math.Abs(d.Percent()) > threshold
+}
+
+// DiffReports 比較兩份報告的主要指標
+func DiffReports(old, cur Report) []ReportDiff {
+	diffs := []ReportDiff{
+		{Metric: "total_ms", Old: old.TotalMs, New: cur.TotalMs},
+		{Metric: "processed", Old: float64(old.Processed), New: float64(cur.Processed), HigherIsBetter: true},
+		{Metric: "failures.crashes", Old: float64(old.Failures.Crashes), New: float64(cur.Failures.Crashes)},
+		{Metric: "failures.lost_ms", Old: old.Failures.LostMs, New: cur.Failures.LostMs},
+		{Metric: "changeover_ms", Old: old.ChangeoverMs, New: cur.ChangeoverMs},
+		{Metric: "max_lateness_ms", Old: old.MaxLatenessMs, New: cur.MaxLatenessMs},
+	}
+
+	oldTypes := make(map[string]TypeReport, len(old.Types))
+	for _, t := range old.Types {
+		oldTypes[t.Type] = t
+	}
+	for _, t := range cur.Types {
+		o := oldTypes[t.Type]
+		diffs = append(diffs,
+			ReportDiff{Metric: "types." + t.Type + ".avg_ms", Old: o.AvgMs, New: t.AvgMs},
+			ReportDiff{Metric: "types." + t.Type + ".wait_ms", Old: o.WaitMs, New: t.WaitMs},
+		)
+	}
+
+	oldClasses := make(map[string]ClassReport, len(old.Classes))
+	for _, c := range old.Classes {
+		oldClasses[c.Class] = c
+	}
+	for _, c := range cur.Classes {
+		o := oldClasses[c.Class]
+		diffs = append(diffs, ReportDiff{Metric: "classes." + c.Class + ".throughput", Old: o.Throughput, New: c.Throughput, Info: true})
+	}
+
+	oldEmployees := make(map[int]EmployeeReport, len(old.Employees))
+	for _, e := range old.Employees {
+		oldEmployees[e.ID] = e
+	}
+	for _, e := range cur.Employees {
+		o := oldEmployees[e.ID]
+		prefix := fmt.Sprintf("employees.%d.", e.ID)
+		diffs = append(diffs,
+			ReportDiff{Metric: prefix + "processed", Old: float64(o.Processed), New: float64(e.Processed), Info: true},
+			ReportDiff{Metric: prefix + "idle_ms", Old: o.IdleMs, New: e.IdleMs, Info: true},
+		)
+	}
+	return diffs
+}
+
+// runDiff 實作 diff 子指令: 比較兩份 JSON 報告, 有指標變差超過門檻時回傳 1
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	threshold := fs.Float64("threshold", 5, "指標變差超過多少百分比視為退步")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(stderr, "用法: assembly_line diff [-threshold 5] old.json new.json")
+		return 2
+	}
+
+	var reports [2]Report
+	for i, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "讀取報告失敗: %v\n", err)
+			return 2
+		}
+		reports[i], err = ReadReport(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(stderr, "解析報告 %s 失敗: %v\n", path, err)
+			return 2
+		}
+	}
+
+	regressions := 0
+	fmt.Fprintf(stdout, "%-28s %12s %12s %12s %9s\n", "指標", "舊", "新", "差異", "%")
+	for _, d := range DiffReports(reports[0], reports[1]) {
+		mark := ""
+		if d.Regressed(*threshold) {
+			mark = "  退步"
+			regressions++
+		}
+		fmt.Fprintf(stdout, "%-28s %12.3f %12.3f %+12.3f %+8.1f%%%s\n",
+			d.Metric, d.Old, d.New, d.Delta(), d.Percent(), mark)
+	}
+	if regressions > 0 {
+		fmt.Fprintf(stdout, "\n共 %d 項指標退步超過 %.1f%%\n", regressions, *threshold)
+		return 1
+	}
+	return 0
+}
+
+// flagConfig 收集所有 flag 目前的值, 作為報告中的設定
+func flagConfig(fs *flag.FlagSet) map[string]string {
+	config := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		config[f.Name] = f.Value.String()
+	})
+	return config
+}
+
+// markdownEscape 跳脫 Markdown 表格中的直線符號
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testStats 產生固定內容的統計結果
+func testStats() Stats {
+	return Stats{
+		Start:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		TotalTime: 1500 * time.Millisecond,
+		Submitted: 3,
+		Employees: []EmployeeStats{
+			{ID: 1, Processed: 2, Busy: time.Second, Idle: 500 * time.Millisecond},
+			{ID: 2, Processed: 1, Busy: 200 * time.Millisecond, Crashes: 1},
+		},
+		Types: map[string]TypeStats{
+			"Item1": {Processed: 2, Busy: 200 * time.Millisecond},
+			"Item3": {Processed: 1, Busy: 200 * time.Millisecond, Wait: 50 * time.Millisecond},
+		},
+		Crashes:    1,
+		Reassigned: 1,
+		LostTime:   100 * time.Millisecond,
+	}
+}
+
+// TestReport_JSONRoundTrip 驗證報告寫成 JSON 再讀回內容不變
+func TestReport_JSONRoundTrip(t *testing.T) {
+	r := NewReport(testStats(), 42, map[string]string{"dispatch": "fifo"})
+	if r.TotalMs != 1500 || r.Processed != 3 {
+		t.Errorf("TotalMs = %v, Processed = %d, want 1500, 3", r.TotalMs, r.Processed)
+	}
+	if len(r.Types) != 2 || r.Types[0].Type != "Item1" || r.Types[0].AvgMs != 100 {
+		t.Errorf("Types = %+v, want Item1 first with avg 100ms", r.Types)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	got, err := ReadReport(&buf)
+	if err != nil {
+		t.Fatalf("ReadReport() error = %v", err)
+	}
+	if got.Seed != 42 || got.Config["dispatch"] != "fifo" || got.Failures.Crashes != 1 {
+		t.Errorf("round trip = %+v", got)
+	}
+	if len(got.Employees) != 2 || got.Employees[1].Crashes != 1 {
+		t.Errorf("Employees = %+v", got.Employees)
+	}
+}
+
+// TestReport_Markdown 驗證 Markdown 報告包含摘要及各表格
+func TestReport_Markdown(t *testing.T) {
+	s := testStats()
+	s.Employees[0].Blocked = 250 * time.Millisecond
+	s.Types["x|y"] = TypeStats{Processed: 1, Busy: time.Millisecond}
+	r := NewReport(s, 42, map[string]string{"type-rate": "a|b"})
+	var buf bytes.Buffer
+	if err := r.WriteMarkdown(&buf); err != nil {
+		t.Fatalf("WriteMarkdown() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"| 總處理時間 | 1500.000 ms |", "| Seed | 42 |", "| 故障 |", "`a\\|b`", "| #2 | 1 |", "| Item3 | 1 |",
+		"| #1 | 2 | 1000.000 | 500.000 | 0.000 | 0.000 | 250.000 | 0 |", "| x\\|y | 1 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+// TestDiffReports 驗證指標退步判斷
+func TestDiffReports(t *testing.T) {
+	old := NewReport(testStats(), 1, nil)
+	s := testStats()
+	s.TotalTime = 2 * time.Second
+	// 總處理量不變, 只是員工之間的分配改變
+	s.Employees[0].Processed, s.Employees[1].Processed = 1, 2
+	cur := NewReport(s, 1, nil)
+
+	regressed := make(map[string]bool)
+	for _, d := range DiffReports(old, cur) {
+		if d.Regressed(5) {
+			regressed[d.Metric] = true
+		}
+	}
+	if !regressed["total_ms"] {
+		t.Error("total_ms not reported as regression")
+	}
+	if regressed["employees.1.processed"] {
+		t.Error("per-employee distribution reported as regression")
+	}
+	if len(regressed) != 1 {
+		t.Errorf("regressions = %v, want only total_ms", regressed)
+	}
+}
+
+// TestRunDiff 驗證 diff 子指令的輸出及結束碼
+func TestRunDiff(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, s Stats) string {
+		path := filepath.Join(dir, name)
+		var buf bytes.Buffer
+		if err := NewReport(s, 1, nil).WriteJSON(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	old := write("old.json", testStats())
+	slow := testStats()
+	slow.TotalTime = 3 * time.Second
+	cur := write("new.json", slow)
+
+	var stdout, stderr bytes.Buffer
+	if code := runDiff([]string{old, old}, &stdout, &stderr); code != 0 {
+		t.Errorf("same report exit code = %d, want 0", code)
+	}
+	stdout.Reset()
+	if code := runDiff([]string{"-threshold", "10", old, cur}, &stdout, &stderr); code != 1 {
+		t.Errorf("regressed exit code = %d, want 1", code)
+	}
+	if !strings.Contains(stdout.String(), "total_ms") || !strings.Contains(stdout.String(), "退步") {
+		t.Errorf("diff output = %s", stdout.String())
+	}
+	if code := runDiff([]string{old}, &stdout, &stderr); code != 2 {
+		t.Errorf("missing argument exit code = %d, want 2", code)
+	}
+}
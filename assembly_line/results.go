@@ -0,0 +1,254 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Producer 可選介面, 處理後產生輸出值, 實作者以 ProcessResult 取代 Process
+type Producer interface {
+	ProcessResult() (any, error)
+}
+
+// Result 一件物品的處理結果
+type Result struct {
+	// Seq 物品在 Run 中的提交順序 (從 0 起算)
+	Seq      int
+	Item     Item
+	Value    any
+	Err      error
+	Employee int
+	Finished time.Time
+	// Buffered 完成後在重排緩衝區等待輸出的時間
+	Buffered time.Duration
+}
+
+// ResultOrder 結果輸出的順序
+type ResultOrder int
+
+const (
+	// CompletionOrder 依完成順序輸出
+	CompletionOrder ResultOrder = iota
+	// SubmissionOrder 依提交順序輸出, 先完成的結果暫存在重排緩衝區
+	SubmissionOrder
+)
+
+// WithResults 收集每件物品的處理結果至 Stats.Results
+func WithResults(order ResultOrder) Option {
+	return func(l *AssemblyLine) {
+		l.resultOrder = &order
+	}
+}
+
+// WithResultSink 收集結果時同時送到 sink, 第一次 Run 或 Stop 結束時關閉 sink;
+// 之後再次執行仍收集結果至 Stats.Results, 但不再送到 sink
+func WithResultSink(sink chan<- Result) Option {
+	return func(l *AssemblyLine) {
+		l.resultSink = sink
+	}
+}
+
+// WithHistory 長時間運行 (Start 之後以 Submit 加入物品) 時, Stats 的時間軸及結果
+// 只保留最近 n 筆, n 小於 0 時全部保留; 預設不保留. Run 一律全部保留
+func WithHistory(n int) Option {
+	return func(l *AssemblyLine) {
+		l.history = n
+	}
+}
+
+// keepRecent 加入 v, n 大於等於 0 時只需保留最後 n 筆, 以 recent 取出;
+// 累積到 2n 筆時才搬移, 平均每次加入為常數時間
+func keepRecent[T any](s []T, v T, n int) []T {
+	if n == 0 {
+		return s
+	}
+	s = append(s, v)
+	if n > 0 && len(s) >= 2*n {
+		s = append(s[:0], s[len(s)-n:]...)
+	}
+	return s
+}
+
+// recent 回傳以 keepRecent 保留的最後 n 筆, n 小於 0 時回傳全部
+func recent[T any](s []T, n int) []T {
+	if n >= 0 && len(s) > n {
+		return s[len(s)-n:]
+	}
+	return s
+}
+
+// WithSequencer 依提交順序輸出結果, 最多只暫存 window 件之內的提前完成物品;
+// 超出範圍的員工會等待前面的物品完成後才繼續
+func WithSequencer(window int) Option {
+	return func(l *AssemblyLine) {
+		order := SubmissionOrder
+		l.resultOrder = &order
+		l.reorderWindow = window
+	}
+}
+
+// SequencerStats 重排緩衝區的統計
+type SequencerStats struct {
+	Window int
+	// TotalWait 所有結果在緩衝區等待的總時間
+	TotalWait time.Duration
+	MaxWait   time.Duration
+	// Blocked 員工因緩衝區已滿而等待的總時間
+	Blocked time.Duration
+	// Overflows 為避免所有員工互相等待而超出範圍暫存的次數
+	Overflows int
+}
+
+// resultCollector 依設定的順序收集結果
+type resultCollector struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	order ResultOrder
+	sink  chan<- Result
+	// results 以 keepRecent 保留最後 keep 筆, emitted 為輸出的總筆數
+	results []Result
+	keep    int
+	emitted int
+	next    int
+	pending map[int]Result
+	// outbox 待送到 sink 的結果; sending 是否已有員工在鎖外依序送出
+	outbox  []Result
+	sending bool
+
+	// window 大於 0 時限制 Seq 只能領先 next 不到 window,
+	// workers 回傳仍在工作的員工數, 至少保留一位員工不等待以免卡死
+	window  int
+	workers func() int
+	blocked int
+	stats   SequencerStats
+}
+
+func newResultCollector(order ResultOrder, sink chan<- Result) *resultCollector {
+	c := &resultCollector{
+		order:   order,
+		sink:    sink,
+		keep:    -1,
+		pending: make(map[int]Result),
+	}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// add 加入一件完成的結果, 依提交順序時只輸出連續的部分,
+// 回傳因緩衝區已滿或等待 sink 接收而等待的時間
+func (c *resultCollector) add(r Result) time.Duration {
+	blocked := c.buffer(r)
+	return blocked + c.deliver()
+}
+
+// buffer 將結果放入緩衝區並輸出可以輸出的部分, 回傳因緩衝區已滿而等待的時間
+func (c *resultCollector) buffer(r Result) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.order == CompletionOrder {
+		c.emit(r)
+		return 0
+	}
+
+	var blocked time.Duration
+	if c.window > 0 && r.Seq >= c.next+c.window {
+		start := time.Now()
+		for r.Seq >= c.next+c.window && c.blocked+1 < c.workers() {
+			c.blocked++
+			c.cond.Wait()
+			c.blocked--
+		}
+		blocked = time.Since(start)
+		c.stats.Blocked += blocked
+		if r.Seq >= c.next+c.window {
+			c.stats.Overflows++
+		}
+	}
+
+	c.pending[r.Seq] = r
+	advanced := false
+	for {
+		next, ok := c.pending[c.next]
+		if !ok {
+			break
+		}
+		delete(c.pending, c.next)
+		c.next++
+		c.emit(next)
+		advanced = true
+	}
+	if advanced {
+		c.cond.Broadcast()
+	}
+	return blocked
+}
+
+// deliver 在鎖外依序將 outbox 送到 sink, 同一時間只有一位員工負責送出,
+// 以免接收端緩慢時所有員工都卡在鎖上; 回傳等待 sink 的時間
+func (c *resultCollector) deliver() time.Duration {
+	c.mu.Lock()
+	if c.sending || len(c.outbox) == 0 {
+		c.mu.Unlock()
+		return 0
+	}
+	c.sending = true
+	start := time.Now()
+	for len(c.outbox) > 0 {
+		out := c.outbox
+		c.outbox = nil
+		c.mu.Unlock()
+		for _, r := range out {
+			c.sink <- r
+		}
+		c.mu.Lock()
+	}
+	c.sending = false
+	c.mu.Unlock()
+	return time.Since(start)
+}
+
+// wake 員工人數變動時, 讓等待中的員工重新檢查是否需要繼續等待
+func (c *resultCollector) wake() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cond.Broadcast()
+}
+
+// flush 輸出緩衝區中剩餘的結果 (前面有物品未處理時), 並關閉 sink;
+// 需在所有員工結束後呼叫
+func (c *resultCollector) flush() {
+	c.mu.Lock()
+	seqs := make([]int, 0, len(c.pending))
+	for seq := range c.pending {
+		seqs = append(seqs, seq)
+	}
+	sort.Ints(seqs)
+	for _, seq := range seqs {
+		c.emit(c.pending[seq])
+		delete(c.pending, seq)
+	}
+	c.mu.Unlock()
+
+	c.deliver()
+	if c.sink != nil {
+		close(c.sink)
+	}
+}
+
+// emit 輸出結果, 送往 sink 的部分先放入 outbox; 呼叫時需持有鎖
+func (c *resultCollector) emit(r Result) {
+	if !r.Finished.IsZero() {
+		r.Buffered = time.Since(r.Finished)
+		c.stats.TotalWait += r.Buffered
+		if r.Buffered > c.stats.MaxWait {
+			c.stats.MaxWait = r.Buffered
+		}
+	}
+	c.results = keepRecent(c.results, r, c.keep)
+	c.emitted++
+	if c.sink != nil {
+		c.outbox = append(c.outbox, r)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// upperItem 將字串轉成大寫的測試物品, 處理時間可自訂
+type upperItem struct {
+	testItem
+	in string
+}
+
+func (i *upperItem) ProcessResult() (any, error) {
+	i.Process()
+	if i.in == "" {
+		return nil, errors.New("empty input")
+	}
+	return strings.ToUpper(i.in), nil
+}
+
+// TestResultCollector_SubmissionOrder 驗證重排緩衝區依提交順序輸出
+func TestResultCollector_SubmissionOrder(t *testing.T) {
+	c := newResultCollector(SubmissionOrder, nil)
+	for _, seq := range []int{2, 0, 3, 1} {
+		c.add(Result{Seq: seq})
+		if seq == 0 && len(c.results) != 1 {
+			t.Fatalf("after seq 0 emitted %d results, want 1", len(c.results))
+		}
+	}
+	for i, r := range c.results {
+		if r.Seq != i {
+			t.Errorf("result %d seq = %d, want %d", i, r.Seq, i)
+		}
+	}
+}
+
+// TestResultCollector_Flush 驗證缺少的物品不會擋住其餘結果
+func TestResultCollector_Flush(t *testing.T) {
+	sink := make(chan Result, 3)
+	c := newResultCollector(SubmissionOrder, sink)
+	c.add(Result{Seq: 2})
+	c.add(Result{Seq: 1})
+	c.flush()
+
+	var seqs []int
+	for r := range sink {
+		seqs = append(seqs, r.Seq)
+	}
+	if len(seqs) != 2 || seqs[0] != 1 || seqs[1] != 2 {
+		t.Errorf("flushed seqs = %v, want [1 2]", seqs)
+	}
+}
+
+// TestAssemblyLine_Results 驗證 Producer 的輸出依提交順序收集
+func TestAssemblyLine_Results(t *testing.T) {
+	inputs := []string{"a", "bb", "", "ccc", "dd"}
+	items := make([]Item, len(inputs))
+	for i, in := range inputs {
+		// 越前面的物品處理越久, 完成順序與提交順序相反
+		d := time.Duration(len(inputs)-i) * 5 * time.Millisecond
+		items[i] = &upperItem{testItem: testItem{kind: "upper", id: i + 1, d: d}, in: in}
+	}
+
+	line := NewAssemblyLine(5, WithResults(SubmissionOrder))
+	stats := line.Run(items)
+
+	if len(stats.Results) != len(inputs) {
+		t.Fatalf("len(Results) = %d, want %d", len(stats.Results), len(inputs))
+	}
+	for i, r := range stats.Results {
+		if r.Seq != i || r.Item != items[i] {
+			t.Errorf("result %d = seq %d %s, want seq %d %s", i, r.Seq, r.Item, i, items[i])
+		}
+		if inputs[i] == "" {
+			if r.Err == nil {
+				t.Errorf("result %d err = nil, want error", i)
+			}
+			continue
+		}
+		if r.Value != strings.ToUpper(inputs[i]) {
+			t.Errorf("result %d value = %v, want %s", i, r.Value, strings.ToUpper(inputs[i]))
+		}
+	}
+}
+
+// TestAssemblyLine_ResultSink 驗證結果依完成順序送到 sink, Run 結束時關閉
+func TestAssemblyLine_ResultSink(t *testing.T) {
+	sink := make(chan Result, 10)
+	line := NewAssemblyLine(2, WithResultSink(sink))
+	items := newTestItems(5, time.Millisecond, "A")
+	stats := line.Run(items)
+
+	n := 0
+	for r := range sink {
+		if r.Value != nil {
+			t.Errorf("non-producer value = %v, want nil", r.Value)
+		}
+		n++
+	}
+	if n != 5 || len(stats.Results) != 5 {
+		t.Errorf("sink received %d, Results %d, want 5", n, len(stats.Results))
+	}
+}
+
+// partsItem 含有 slice 而無法比較的測試物品
+type partsItem struct{ parts []string }
+
+func (i partsItem) Process()       {}
+func (i partsItem) String() string { return strings.Join(i.parts, "+") }
+
+// TestAssemblyLine_ResultsPerSubmission 驗證同一件物品提交多次時各自有結果,
+// 且不可比較的物品也能處理
+func TestAssemblyLine_ResultsPerSubmission(t *testing.T) {
+	a := &testItem{kind: "A", id: 1, d: time.Millisecond}
+	b := &testItem{kind: "A", id: 2, d: time.Millisecond}
+	stats := NewAssemblyLine(2, WithResults(SubmissionOrder)).Run([]Item{a, b, a})
+
+	if len(stats.Results) != 3 {
+		t.Fatalf("len(Results) = %d, want 3", len(stats.Results))
+	}
+	for i, want := range []Item{a, b, a} {
+		if r := stats.Results[i]; r.Seq != i || r.Item != want {
+			t.Errorf("result %d = seq %d %s, want seq %d %s", i, r.Seq, r.Item, i, want)
+		}
+	}
+	if err := stats.Reconcile(); err != nil {
+		t.Errorf("Reconcile() = %v, want nil", err)
+	}
+
+	items := []Item{partsItem{[]string{"a", "b"}}, partsItem{[]string{"c"}}}
+	for _, opts := range [][]Option{nil, {WithResults(SubmissionOrder)}} {
+		stats := NewAssemblyLine(2, opts...).Run(items)
+		if stats.TotalProcessed() != 2 {
+			t.Errorf("TotalProcessed() = %d, want 2", stats.TotalProcessed())
+		}
+	}
+}
+
+// TestAssemblyLine_History 驗證長時間運行時時間軸及結果只保留最近的部分, 預設不保留
+func TestAssemblyLine_History(t *testing.T) {
+	run := func(opts ...Option) Stats {
+		line := NewAssemblyLine(1, append(opts, WithResults(SubmissionOrder))...)
+		line.Start()
+		for _, item := range newTestItems(5, 0, "A") {
+			if err := line.Submit(item); err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+		}
+		return line.Stop()
+	}
+
+	stats := run(WithHistory(2))
+	if len(stats.Results) != 2 || stats.DroppedResults != 3 || len(stats.Timeline) != 2 {
+		t.Errorf("Results = %d, DroppedResults = %d, Timeline = %d, want 2, 3, 2",
+			len(stats.Results), stats.DroppedResults, len(stats.Timeline))
+	}
+	if len(stats.Results) == 2 && stats.Results[1].Seq != 4 {
+		t.Errorf("last result seq = %d, want 4", stats.Results[1].Seq)
+	}
+	if err := stats.Reconcile(); err != nil {
+		t.Errorf("Reconcile() = %v, want nil", err)
+	}
+
+	stats = run()
+	if stats.Results != nil || stats.Timeline != nil || stats.DroppedResults != 5 || stats.Submitted != 5 {
+		t.Errorf("default Results = %d, Timeline = %d, DroppedResults = %d, Submitted = %d, want none kept of 5",
+			len(stats.Results), len(stats.Timeline), stats.DroppedResults, stats.Submitted)
+	}
+}
+
+// TestAssemblyLine_ResultSinkRunTwice 驗證 sink 只在第一次執行後關閉, 再次執行不會送到已關閉的 sink
+func TestAssemblyLine_ResultSinkRunTwice(t *testing.T) {
+	sink := make(chan Result, 10)
+	line := NewAssemblyLine(2, WithResultSink(sink))
+	line.Run(newTestItems(3, 0, "A"))
+	stats := line.Run(newTestItems(2, 0, "A"))
+
+	n := 0
+	for range sink {
+		n++
+	}
+	if n != 3 || len(stats.Results) != 2 {
+		t.Errorf("sink received %d, second Results %d, want 3 and 2", n, len(stats.Results))
+	}
+}
+
+// TestAssemblyLine_SlowResultSink 驗證接收緩慢的 sink 不會卡住其他員工, 等待時間計為 Blocked
+func TestAssemblyLine_SlowResultSink(t *testing.T) {
+	sink := make(chan Result)
+	line := NewAssemblyLine(2, WithResultSink(sink))
+	var processed int32
+	items := newTestItems(4, time.Millisecond, "A")
+	for _, item := range items {
+		item.(*testItem).processed = &processed
+	}
+
+	// 開始接收前, 沒有負責送出的員工仍應處理完其餘物品
+	before := make(chan int32, 1)
+	go func() {
+		deadline := time.Now().Add(time.Second)
+		for atomic.LoadInt32(&processed) < 4 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(20 * time.Millisecond)
+		before <- atomic.LoadInt32(&processed)
+		for range sink {
+		}
+	}()
+	stats := line.Run(items)
+
+	var blocked time.Duration
+	for _, e := range stats.Employees {
+		blocked += e.Blocked
+	}
+	if n := <-before; n != 4 || blocked < 20*time.Millisecond {
+		t.Errorf("processed before receiving = %d, Blocked = %v, want 4 and at least 20ms", n, blocked)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Schedule 員工的班表, 時間皆以流水線開始執行起算
+type Schedule struct {
+	// ShiftStart 上班時間
+	ShiftStart time.Duration
+	// ShiftEnd 下班時間, 0 表示不限
+	ShiftEnd time.Duration
+	// BreakEvery 每處理幾件物品休息一次, 0 表示不限
+	BreakEvery int
+	// BreakAfter 連續工作多久後休息一次, 0 表示不限
+	BreakAfter time.Duration
+	// BreakLength 每次休息的時間
+	BreakLength time.Duration
+}
+
+// deadline 下班時間點, 沒有下班時間時回傳 false
+func (s Schedule) deadline(start time.Time) (time.Time, bool) {
+	if s.ShiftEnd <= 0 {
+		return time.Time{}, false
+	}
+	return start.Add(s.ShiftEnd), true
+}
+
+// dueBreak 是否該休息了
+func (s Schedule) dueBreak(items int, worked time.Duration) bool {
+	if s.BreakLength <= 0 {
+		return false
+	}
+	return s.BreakEvery > 0 && items >= s.BreakEvery ||
+		s.BreakAfter > 0 && worked >= s.BreakAfter
+}
+
+// WithSchedules 依序設定每位員工的班表, 多出的員工全程在班
+func WithSchedules(schedules ...Schedule) Option {
+	return func(l *AssemblyLine) {
+		l.schedules = schedules
+	}
+}
+
+// awaitShift 依班表讓員工等到上班, 下班後回傳 false
+func (l *AssemblyLine) awaitShift(e *Employee) bool {
+	s := e.Schedule
+	if wait := s.ShiftStart - time.Since(l.startTime); wait > 0 {
+		fmt.Fprintf(l.out, "[%s] 員工 #%d 尚未上班, %v 後開始\n",
+			time.Now().Format(timeLayout), e.ID, wait)
+		e.setStatus(statusOffShift, nil)
+		time.Sleep(wait)
+		// 上班前整段時間都算不在班
+		e.unavailable += s.ShiftStart
+		e.workingSince = time.Now()
+	}
+
+	if end, ok := s.deadline(l.startTime); ok && !time.Now().Before(end) {
+		fmt.Fprintf(l.out, "[%s] 員工 #%d 下班\n", time.Now().Format(timeLayout), e.ID)
+		e.setStatus(statusOffShift, nil)
+		e.offAt = time.Since(l.startTime)
+		return false
+	}
+
+	if s.dueBreak(e.sinceBreak, time.Since(e.workingSince)) {
+		fmt.Fprintf(l.out, "[%s] 員工 #%d 休息 %v\n",
+			time.Now().Format(timeLayout), e.ID, s.BreakLength)
+		e.setStatus(statusBreak, nil)
+		time.Sleep(s.BreakLength)
+		e.unavailable += s.BreakLength
+		e.sinceBreak = 0
+		e.workingSince = time.Now()
+		e.setStatus(statusIdle, nil)
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestScheduleDueBreak 驗證休息條件
+func TestScheduleDueBreak(t *testing.T) {
+	tests := []struct {
+		name   string
+		s      Schedule
+		items  int
+		worked time.Duration
+		want   bool
+	}{
+		{"no break length", Schedule{BreakEvery: 1}, 5, 0, false},
+		{"by items", Schedule{BreakEvery: 3, BreakLength: time.Millisecond}, 3, 0, true},
+		{"not enough items", Schedule{BreakEvery: 3, BreakLength: time.Millisecond}, 2, 0, false},
+		{"by time", Schedule{BreakAfter: time.Second, BreakLength: time.Millisecond}, 0, time.Second, true},
+		{"not enough time", Schedule{BreakAfter: time.Second, BreakLength: time.Millisecond}, 0, time.Millisecond, false},
+	}
+	for _, tt := range tests {
+		if got := tt.s.dueBreak(tt.items, tt.worked); got != tt.want {
+			t.Errorf("%s: dueBreak = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestAssemblyLine_ShiftEnd 驗證下班後不再接物品, 剩餘物品留在線上
+func TestAssemblyLine_ShiftEnd(t *testing.T) {
+	line := NewAssemblyLine(1, WithSchedules(Schedule{ShiftEnd: 25 * time.Millisecond}))
+	stats := line.Run(newTestItems(10, 10*time.Millisecond, "A"))
+
+	if got := stats.TotalProcessed(); got < 2 || got > 4 {
+		t.Errorf("TotalProcessed = %d, want 2-4 before shift end", got)
+	}
+	if stats.Submitted != 10 {
+		t.Errorf("Submitted = %d, want 10", stats.Submitted)
+	}
+}
+
+// TestAssemblyLine_ShiftStart 驗證尚未上班的員工不會接物品, 等待時間算不在班
+func TestAssemblyLine_ShiftStart(t *testing.T) {
+	line := NewAssemblyLine(2, WithSchedules(Schedule{}, Schedule{ShiftStart: time.Second}))
+	stats := line.Run(newTestItems(5, time.Millisecond, "A"))
+
+	if stats.Employees[0].Processed != 5 {
+		t.Errorf("employee 1 processed %d, want 5", stats.Employees[0].Processed)
+	}
+	if stats.Employees[1].Processed != 0 {
+		t.Errorf("employee 2 processed %d, want 0", stats.Employees[1].Processed)
+	}
+	if stats.Employees[1].Unavailable < time.Second {
+		t.Errorf("employee 2 unavailable = %v, want >= 1s", stats.Employees[1].Unavailable)
+	}
+}
+
+// TestAssemblyLine_Breaks 驗證休息時間與忙碌、閒置時間分開計算
+func TestAssemblyLine_Breaks(t *testing.T) {
+	s := Schedule{BreakEvery: 2, BreakLength: 20 * time.Millisecond}
+	line := NewAssemblyLine(1, WithSchedules(s))
+	stats := line.Run(newTestItems(5, 5*time.Millisecond, "A"))
+
+	e := stats.Employees[0]
+	if e.Processed != 5 {
+		t.Errorf("Processed = %d, want 5", e.Processed)
+	}
+	// 第 2、4 件之後各休息一次
+	if e.Unavailable != 40*time.Millisecond {
+		t.Errorf("Unavailable = %v, want 40ms", e.Unavailable)
+	}
+	if e.Busy < 25*time.Millisecond {
+		t.Errorf("Busy = %v, want >= 25ms", e.Busy)
+	}
+	if sum := e.Busy + e.Idle + e.Unavailable; sum != stats.TotalTime {
+		t.Errorf("Busy+Idle+Unavailable = %v, want TotalTime %v", sum, stats.TotalTime)
+	}
+}
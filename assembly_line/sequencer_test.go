@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAssemblyLine_SequencerOrder 驗證依提交順序輸出, 並記錄在緩衝區的等待時間
+func TestAssemblyLine_SequencerOrder(t *testing.T) {
+	items := []Item{
+		&testItem{kind: "A", id: 1, d: 30 * time.Millisecond},
+		&testItem{kind: "A", id: 2, d: time.Millisecond},
+		&testItem{kind: "A", id: 3, d: time.Millisecond},
+	}
+	line := NewAssemblyLine(3, WithSequencer(3))
+	stats := line.Run(items)
+
+	for i, r := range stats.Results {
+		if r.Item != items[i] {
+			t.Errorf("result %d = %s, want %s", i, r.Item, items[i])
+		}
+	}
+	// 第 2、3 件要等第 1 件完成
+	if b := stats.Results[1].Buffered; b < 20*time.Millisecond {
+		t.Errorf("item 2 buffered = %v, want >= 20ms", b)
+	}
+	if stats.Sequencer == nil {
+		t.Fatal("Sequencer stats = nil")
+	}
+	if stats.Sequencer.MaxWait < 20*time.Millisecond {
+		t.Errorf("MaxWait = %v, want >= 20ms", stats.Sequencer.MaxWait)
+	}
+}
+
+// TestAssemblyLine_SequencerWindow 驗證超出視窗的員工會等待前面的物品完成
+func TestAssemblyLine_SequencerWindow(t *testing.T) {
+	items := []Item{
+		&testItem{kind: "A", id: 1, d: 40 * time.Millisecond},
+		&testItem{kind: "A", id: 2, d: time.Millisecond},
+		&testItem{kind: "A", id: 3, d: time.Millisecond},
+		&testItem{kind: "A", id: 4, d: time.Millisecond},
+	}
+	line := NewAssemblyLine(2, WithSequencer(2))
+	stats := line.Run(items)
+
+	if got := stats.TotalProcessed(); got != 4 {
+		t.Errorf("TotalProcessed = %d, want 4", got)
+	}
+	// 員工 2 完成第 2 件後, 第 3 件超出視窗需等待第 1 件
+	if stats.Sequencer.Blocked < 20*time.Millisecond {
+		t.Errorf("Blocked = %v, want >= 20ms", stats.Sequencer.Blocked)
+	}
+	for i, r := range stats.Results {
+		if r.Item != items[i] {
+			t.Errorf("result %d = %s, want %s", i, r.Item, items[i])
+		}
+	}
+}
+
+// TestAssemblyLine_SequencerNoDeadlock 驗證缺少的物品還在佇列中時, 最後一位員工不會等待
+func TestAssemblyLine_SequencerNoDeadlock(t *testing.T) {
+	// 第 1 件在第一次嘗試時故障並重新排到最後, 單一員工必須超出視窗繼續處理
+	line := NewAssemblyLine(1,
+		WithSequencer(1),
+		WithFailures(Failure{AtAttempts: []int{1}, Recovery: time.Millisecond}))
+
+	done := make(chan Stats)
+	go func() { done <- line.Run(newTestItems(4, time.Millisecond, "A")) }()
+
+	select {
+	case stats := <-done:
+		if stats.TotalProcessed() != 4 {
+			t.Errorf("TotalProcessed = %d, want 4", stats.TotalProcessed())
+		}
+		if stats.Sequencer.Overflows == 0 {
+			t.Error("Overflows = 0, want > 0")
+		}
+		if stats.Results[0].Seq != 0 {
+			t.Errorf("first result seq = %d, want 0", stats.Results[0].Seq)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not finish, sequencer deadlocked")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Strategy 可供比較的派發策略, New 為 nil 時使用先進先出
+type Strategy struct {
+	Name string
+	New  DispatcherFactory
+}
+
+// Strategies 所有內建的派發策略, durations 為依技能派發時使用的各類型耗時
+func Strategies(durations Durations) []Strategy {
+	return []Strategy{
+		{Name: "fifo"},
+		{Name: "spt", New: NewSPTDispatcher},
+		{Name: "lpt", New: NewLPTDispatcher},
+		{Name: "affinity", New: NewAffinityDispatcher},
+		{Name: "steal", New: NewWorkStealingDispatcher},
+		{Name: "skill", New: NewSkillDispatcher(durations)},
+	}
+}
+
+// SimResult 單一策略的模擬結果
+type SimResult struct {
+	Strategy string
+	// Makespan 最後一件物品完成的時間
+	Makespan time.Duration
+	// MeanWait 物品從到達到開始處理的平均等待時間
+	MeanWait time.Duration
+	// Utilization 員工忙碌時間佔 員工數 x Makespan 的比例
+	Utilization    float64
+	Changeovers    int
+	ChangeoverTime time.Duration
+}
+
+// Simulate 以模擬時間執行派發策略, 不實際處理物品:
+// 所有物品在時間 0 到達, 處理時間取自 EstimatedDuration 或 WithDurations 設定的各類型耗時,
+// 並依員工速度及切換時間調整, 每次由最早空閒的員工向派發器取下一件
+func (l *AssemblyLine) Simulate(items []Item, s Strategy) SimResult {
+	var d Dispatcher
+	if s.New != nil {
+		d = s.New(l.employees)
+	} else {
+		d = NewFIFODispatcher(len(items))
+	}
+	for _, item := range items {
+		d.Push(item)
+	}
+	d.Close()
+
+	n := len(l.employees)
+	free := make([]time.Duration, n)
+	drained := make([]bool, n)
+	for _, e := range l.employees {
+		e.lastType = ""
+	}
+
+	res := SimResult{Strategy: s.Name}
+	var busy, wait time.Duration
+	for left := n; left > 0; {
+		// 最早空閒的員工取下一件, 同時空閒時以編號小的優先
+		i := -1
+		for j := range free {
+			if !drained[j] && (i < 0 || free[j] < free[i]) {
+				i = j
+			}
+		}
+		e := l.employees[i]
+		item, err := d.Next(context.Background(), e)
+		if err != nil {
+			drained[i] = true
+			left--
+			continue
+		}
+
+		kind := itemType(item)
+		cost := e.Changeover.Cost(e.lastType, kind)
+		if cost > 0 {
+			res.Changeovers++
+			res.ChangeoverTime += cost
+		}
+		work := cost + scaleDuration(l.durations.Estimate(item), e.Speed.Factor(kind))
+		wait += free[i]
+		busy += work
+		free[i] += work
+		e.lastType = kind
+	}
+
+	for _, t := range free {
+		if t > res.Makespan {
+			res.Makespan = t
+		}
+	}
+	if len(items) > 0 {
+		res.MeanWait = wait / time.Duration(len(items))
+	}
+	if res.Makespan > 0 && n > 0 {
+		res.Utilization = float64(busy) / (float64(res.Makespan) * float64(n))
+	}
+	return res
+}
+
+// Compare 以相同物品依序模擬每種策略
+func (l *AssemblyLine) Compare(items []Item, strategies []Strategy) []SimResult {
+	results := make([]SimResult, 0, len(strategies))
+	for _, s := range strategies {
+		results = append(results, l.Simulate(items, s))
+	}
+	return results
+}
+
+// WriteComparison 以表格輸出各策略的模擬結果
+func WriteComparison(w io.Writer, results []SimResult) error {
+	ew := &errWriter{w: w}
+	ew.printf("%-10s %12s %12s %8s %12s\n", "策略", "完工時間", "平均等待", "使用率", "切換時間")
+	for _, r := range results {
+		ew.printf("%-10s %12v %12v %7.1f%% %12v\n", r.Strategy, r.Makespan.Round(time.Millisecond),
+			r.MeanWait.Round(time.Millisecond), r.Utilization*100, r.ChangeoverTime)
+	}
+	return ew.err
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSimulate_Uniform 驗證相同處理時間的物品平均分給所有員工
+func TestSimulate_Uniform(t *testing.T) {
+	line := NewAssemblyLine(5, WithDurations(Durations{"A": 100 * time.Millisecond}))
+	res := line.Simulate(newTestItems(10, 100*time.Millisecond, "A"), Strategy{Name: "fifo"})
+
+	if res.Makespan != 200*time.Millisecond {
+		t.Errorf("Makespan = %v, want 200ms", res.Makespan)
+	}
+	if res.MeanWait != 50*time.Millisecond {
+		t.Errorf("MeanWait = %v, want 50ms", res.MeanWait)
+	}
+	if res.Utilization != 1 {
+		t.Errorf("Utilization = %v, want 1", res.Utilization)
+	}
+}
+
+// TestSimulate_Strategies 驗證每種策略都模擬完所有物品
+func TestSimulate_Strategies(t *testing.T) {
+	items := []Item{
+		&testItem{kind: "A", id: 1, d: 100 * time.Millisecond},
+		&testItem{kind: "A", id: 2, d: 100 * time.Millisecond},
+		&testItem{kind: "A", id: 3, d: 100 * time.Millisecond},
+		&testItem{kind: "A", id: 4, d: 100 * time.Millisecond},
+		&testItem{kind: "B", id: 1, d: 200 * time.Millisecond},
+	}
+	durations := Durations{"A": 100 * time.Millisecond, "B": 200 * time.Millisecond}
+	line := NewAssemblyLine(2, WithDurations(durations))
+	for _, s := range Strategies(durations) {
+		res := line.Simulate(items, s)
+		// 兩位員工的忙碌時間合計為所有物品的處理時間 600ms
+		busy := time.Duration(res.Utilization * float64(2*res.Makespan)).Round(time.Millisecond)
+		if busy != 600*time.Millisecond {
+			t.Errorf("%s busy = %v, want 600ms", s.Name, busy)
+		}
+		if s.Name == "fifo" && res.Makespan != 400*time.Millisecond {
+			t.Errorf("fifo Makespan = %v, want 400ms", res.Makespan)
+		}
+	}
+}
+
+// TestSimulate_JobOrder 驗證最長優先縮短完工時間, 最短優先縮短平均等待
+func TestSimulate_JobOrder(t *testing.T) {
+	items := []Item{
+		&testItem{kind: "A", id: 1, d: 100 * time.Millisecond},
+		&testItem{kind: "A", id: 2, d: 100 * time.Millisecond},
+		&testItem{kind: "A", id: 3, d: 100 * time.Millisecond},
+		&testItem{kind: "A", id: 4, d: 100 * time.Millisecond},
+		&testItem{kind: "B", id: 1, d: 200 * time.Millisecond},
+	}
+	line := NewAssemblyLine(2)
+	results := make(map[string]SimResult)
+	for _, s := range Strategies(nil) {
+		results[s.Name] = line.Simulate(items, s)
+	}
+
+	if got := results["lpt"].Makespan; got != 300*time.Millisecond {
+		t.Errorf("lpt Makespan = %v, want 300ms", got)
+	}
+	if results["spt"].MeanWait >= results["lpt"].MeanWait {
+		t.Errorf("spt MeanWait = %v, want less than lpt %v",
+			results["spt"].MeanWait, results["lpt"].MeanWait)
+	}
+}
+
+// TestSimulate_Changeover 驗證模擬計入切換時間, 依類型派發可減少切換
+func TestSimulate_Changeover(t *testing.T) {
+	items := make([]Item, 0, 12)
+	for i := 0; i < 6; i++ {
+		items = append(items,
+			&testItem{kind: "A", id: i, d: 100 * time.Millisecond},
+			&testItem{kind: "B", id: i, d: 100 * time.Millisecond})
+	}
+	line := NewAssemblyLine(3, WithDurations(Durations{"A": 100 * time.Millisecond, "B": 100 * time.Millisecond}),
+		WithChangeover(UniformChangeover([]string{"A", "B"}, 50*time.Millisecond)))
+
+	fifo := line.Simulate(items, Strategy{Name: "fifo"})
+	affinity := line.Simulate(items, Strategy{Name: "affinity", New: NewAffinityDispatcher})
+	if affinity.ChangeoverTime >= fifo.ChangeoverTime {
+		t.Errorf("affinity ChangeoverTime = %v, want less than fifo %v",
+			affinity.ChangeoverTime, fifo.ChangeoverTime)
+	}
+	if affinity.Makespan >= fifo.Makespan {
+		t.Errorf("affinity Makespan = %v, want less than fifo %v", affinity.Makespan, fifo.Makespan)
+	}
+}
+
+// TestSimulate_NoProcessing 驗證模擬不實際處理物品
+func TestSimulate_NoProcessing(t *testing.T) {
+	var processed int32
+	items := []Item{&testItem{kind: "A", id: 1, d: time.Hour, processed: &processed}}
+	line := NewAssemblyLine(1, WithDurations(Durations{"A": time.Hour}))
+
+	start := time.Now()
+	res := line.Simulate(items, Strategy{Name: "steal", New: NewWorkStealingDispatcher})
+	if time.Since(start) > time.Second || processed != 0 {
+		t.Errorf("Simulate processed the item (took %v, processed %d)", time.Since(start), processed)
+	}
+	if res.Makespan != time.Hour {
+		t.Errorf("Makespan = %v, want 1h", res.Makespan)
+	}
+}
+
+// TestWriteComparison 驗證比較表格包含每種策略
+func TestWriteComparison(t *testing.T) {
+	line := NewAssemblyLine(3, WithDurations(itemDurations))
+	var buf bytes.Buffer
+	if err := WriteComparison(&buf, line.Compare(newItems(2), Strategies(itemDurations))); err != nil {
+		t.Fatalf("WriteComparison() error = %v", err)
+	}
+	for _, s := range Strategies(itemDurations) {
+		if !strings.Contains(buf.String(), s.Name) {
+			t.Errorf("comparison does not contain %q:\n%s", s.Name, buf.String())
+		}
+	}
+}
+
+// TestSimulate_LPTMakespan 驗證五位員工處理三種物品時,
+// 最長處理時間優先的完工時間不比先進先出長, 且達到理論下限
+func TestSimulate_LPTMakespan(t *testing.T) {
+	line := NewAssemblyLine(numEmployees)
+	lpt := Strategy{Name: "lpt", New: NewLPTDispatcher}
+	// 總處理時間 10 x (100 + 150 + 200) ms 平均分給五位員工
+	lowerBound := 10 * (item1Duration + item2Duration + item3Duration) / numEmployees
+
+	improved := 0
+	for seed := int64(1); seed <= 20; seed++ {
+		items := newItems(10)
+		r := rand.New(rand.NewSource(seed))
+		r.Shuffle(len(items), func(i, j int) {
+			items[i], items[j] = items[j], items[i]
+		})
+
+		fifo := line.Simulate(items, Strategy{Name: "fifo"})
+		got := line.Simulate(items, lpt)
+		if got.Makespan != lowerBound {
+			t.Errorf("seed %d: lpt Makespan = %v, want %v", seed, got.Makespan, lowerBound)
+		}
+		if got.Makespan > fifo.Makespan {
+			t.Errorf("seed %d: lpt Makespan = %v, longer than fifo %v", seed, got.Makespan, fifo.Makespan)
+		}
+		if got.Makespan < fifo.Makespan {
+			improved++
+		}
+	}
+	if improved == 0 {
+		t.Error("lpt never shorter than fifo")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import "time"
+
+// Skill 員工處理各類型物品的速度倍率, 2 表示只需一半時間, 未設定的類型為 1
+type Skill map[string]float64
+
+// Factor 回傳處理 kind 類型物品的速度倍率
+func (s Skill) Factor(kind string) float64 {
+	if f, ok := s[kind]; ok && f > 0 {
+		return f
+	}
+	return 1
+}
+
+// UniformSkill 所有類型都使用相同速度倍率
+func UniformSkill(types []string, speed float64) Skill {
+	s := make(Skill, len(types))
+	for _, kind := range types {
+		s[kind] = speed
+	}
+	return s
+}
+
+// scaleDuration 依速度倍率縮放處理時間
+func scaleDuration(d time.Duration, speed float64) time.Duration {
+	if speed <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) / speed)
+}
+
+// processWithSpeed 以員工速度處理單件物品, 物品實作 Producer 時回傳其輸出
+func processWithSpeed(item Item, speed float64) (value any, err error) {
+	if p, ok := item.(Producer); ok {
+		stretch(speed, func() { value, err = p.ProcessResult() })
+		return value, err
+	}
+	if sp, ok := item.(SpeedProcessor); ok {
+		sp.ProcessWithSpeed(speed)
+		return nil, nil
+	}
+	stretch(speed, item.Process)
+	return nil, nil
+}
+
+// processBatchWithSpeed 以員工速度整批處理物品
+func processBatchWithSpeed(bp BatchProcessor, batch []Item, speed float64) {
+	if sp, ok := bp.(SpeedBatchProcessor); ok {
+		sp.ProcessBatchWithSpeed(batch, speed)
+		return
+	}
+	stretch(speed, func() { bp.ProcessBatch(batch) })
+}
+
+// stretch 執行 fn, 速度低於 1 時依比例延長耗時;
+// 無法縮短已完成的處理, 所以速度高於 1 時維持原耗時
+func stretch(speed float64, fn func()) {
+	start := time.Now()
+	fn()
+	if speed < 1 {
+		elapsed := time.Since(start)
+		time.Sleep(scaleDuration(elapsed, speed) - elapsed)
+	}
+}
+
+// Durations 各類型物品以速度 1 處理的耗時
+type Durations map[string]time.Duration
+
+// Estimate 物品預估的處理時間, 物品實作 Estimator 且有預估時以其為準,
+// 否則依類型查表, 未設定的類型為 0
+func (d Durations) Estimate(item Item) time.Duration {
+	if est := estimate(item); est > 0 {
+		return est
+	}
+	return d[itemType(item)]
+}
+
+// NewSkillDispatcher 依員工技能及各類型的耗時 durations 派發,
+// 讓快的員工處理耗時的物品, 慢的員工處理短的物品
+func NewSkillDispatcher(durations Durations) DispatcherFactory {
+	return func(employees []*Employee) Dispatcher {
+		return newSkillDispatcher(employees, durations)
+	}
+}
+
+func newSkillDispatcher(employees []*Employee, durations Durations) Dispatcher {
+	// 每種類型的團隊平均速度, 在 pick 中使用所以已受派發器的鎖保護
+	avg := make(map[string]float64)
+	avgSpeed := func(kind string) float64 {
+		if v, ok := avg[kind]; ok {
+			return v
+		}
+		v := 1.0
+		if len(employees) > 0 {
+			var sum float64
+			for _, e := range employees {
+				sum += e.Speed.Factor(kind)
+			}
+			v = sum / float64(len(employees))
+		}
+		avg[kind] = v
+		return v
+	}
+
+	return newQueueDispatcher(func(items []Item, e *Employee) int {
+		// 選擇與平均員工相比能省下最多時間的物品,
+		// 慢的員工會選到最不吃虧 (最短) 的物品
+		best, bestSaved := 0, 0.0
+		for i, item := range items {
+			kind := itemType(item)
+			est := float64(durations.Estimate(item))
+			saved := est/avgSpeed(kind) - est/e.Speed.Factor(kind)
+			if i == 0 || saved > bestSaved {
+				best, bestSaved = i, saved
+			}
+		}
+		return best
+	})
+}
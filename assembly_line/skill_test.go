@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestSkillFactor 驗證速度倍率預設值
+func TestSkillFactor(t *testing.T) {
+	s := Skill{"A": 2, "B": 0}
+	if got := s.Factor("A"); got != 2 {
+		t.Errorf("Factor(A) = %v, want 2", got)
+	}
+	if got := s.Factor("B"); got != 1 {
+		t.Errorf("Factor(B) = %v, want 1 for invalid speed", got)
+	}
+	var empty Skill
+	if got := empty.Factor("A"); got != 1 {
+		t.Errorf("nil Skill Factor = %v, want 1", got)
+	}
+}
+
+// TestScaleDuration 驗證處理時間依速度縮放
+func TestScaleDuration(t *testing.T) {
+	tests := []struct {
+		speed float64
+		want  time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 50 * time.Millisecond},
+		{0.5, 200 * time.Millisecond},
+		{0, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := scaleDuration(100*time.Millisecond, tt.speed); got != tt.want {
+			t.Errorf("scaleDuration(100ms, %v) = %v, want %v", tt.speed, got, tt.want)
+		}
+	}
+}
+
+// TestProcessWithSpeed 驗證員工速度影響處理時間
+func TestProcessWithSpeed(t *testing.T) {
+	tests := []struct {
+		name  string
+		item  Item
+		speed float64
+		want  time.Duration
+	}{
+		{"Item1 fast", &Item1{ID: 1}, 2, 50 * time.Millisecond},
+		{"Item3 slow", &Item3{ID: 1}, 0.8, 250 * time.Millisecond},
+		{"stretched", &testItem{kind: "A", d: 20 * time.Millisecond}, 0.5, 40 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			processWithSpeed(tt.item, tt.speed)
+			got := time.Since(start)
+			if got < tt.want || got > tt.want+15*time.Millisecond {
+				t.Errorf("duration = %v, want about %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestProcessBatchWithSpeed 驗證整批處理也依員工速度縮放, 快的員工不會因批次變慢
+func TestProcessBatchWithSpeed(t *testing.T) {
+	var batches int32
+	slow := []Item{
+		&batchTestItem{testItem: testItem{kind: "A", id: 1, d: 10 * time.Millisecond}, batches: &batches},
+		&batchTestItem{testItem: testItem{kind: "A", id: 2, d: 10 * time.Millisecond}, batches: &batches},
+	}
+	tests := []struct {
+		name  string
+		batch []Item
+		speed float64
+		want  time.Duration
+	}{
+		// 四件 Item1 一批需 250ms, 速度 4 時為四分之一
+		{"Item1 fast", []Item{&Item1{ID: 1}, &Item1{ID: 2}, &Item1{ID: 3}, &Item1{ID: 4}}, 4, 62500 * time.Microsecond},
+		{"stretched", slow, 0.5, 40 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			processBatchWithSpeed(tt.batch[0].(BatchProcessor), tt.batch, tt.speed)
+			got := time.Since(start)
+			if got < tt.want || got > tt.want+15*time.Millisecond {
+				t.Errorf("duration = %v, want about %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSkillDispatcher 驗證快的員工拿到耗時的物品, 慢的員工拿到短的物品
+func TestSkillDispatcher(t *testing.T) {
+	types := []string{"A", "B", "C"}
+	fast := &Employee{ID: 1, Speed: UniformSkill(types, 2)}
+	slow := &Employee{ID: 2, Speed: UniformSkill(types, 0.5)}
+	durations := Durations{"A": 10 * time.Millisecond, "B": 20 * time.Millisecond, "C": 30 * time.Millisecond}
+	d := NewSkillDispatcher(durations)([]*Employee{fast, slow})
+
+	short := &testItem{kind: "A"}
+	medium := &testItem{kind: "B"}
+	long := &testItem{kind: "C"}
+	for _, item := range []Item{medium, short, long} {
+		d.Push(item)
+	}
+	d.Close()
+
+	if got, _ := d.Next(context.Background(), fast); got != long {
+		t.Errorf("fast employee got %s, want %s", got, long)
+	}
+	if got, _ := d.Next(context.Background(), slow); got != short {
+		t.Errorf("slow employee got %s, want %s", got, short)
+	}
+}
+
+// TestAssemblyLine_Skills 驗證速度倍率套用到對應員工
+func TestAssemblyLine_Skills(t *testing.T) {
+	line := NewAssemblyLine(2, WithSkills(Skill{"A": 2}))
+	if got := line.Employees()[0].Speed.Factor("A"); got != 2 {
+		t.Errorf("employee 1 speed = %v, want 2", got)
+	}
+	if got := line.Employees()[1].Speed.Factor("A"); got != 1 {
+		t.Errorf("employee 2 speed = %v, want 1", got)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 時間軸上每段處理的結果
+const (
+	OutcomeCompleted = "completed"
+	OutcomeCrashed   = "crashed"
+)
+
+// Span 員工處理一件物品的一段時間
+type Span struct {
+	Employee int
+	Item     string
+	Type     string
+	Start    time.Time
+	End      time.Time
+	Outcome  string
+}
+
+// addSpan 記錄一段處理時間
+func (l *AssemblyLine) addSpan(e *Employee, item Item, start, end time.Time, outcome string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.timeline = keepRecent(l.timeline, Span{
+		Employee: e.ID,
+		Item:     item.String(),
+		Type:     itemType(item),
+		Start:    start,
+		End:      end,
+		Outcome:  outcome,
+	}, l.keep)
+}
+
+// millis 相對 origin 的毫秒數
+func millis(t, origin time.Time) string {
+	return strconv.FormatFloat(float64(t.Sub(origin))/float64(time.Millisecond), 'f', 3, 64)
+}
+
+// WriteTimelineCSV 輸出時間軸 CSV, 時間為相對 origin 的毫秒數
+func WriteTimelineCSV(w io.Writer, spans []Span, origin time.Time) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"employee", "item", "type", "start_ms", "end_ms", "outcome"}); err != nil {
+		return err
+	}
+	for _, s := range spans {
+		record := []string{
+			strconv.Itoa(s.Employee),
+			s.Item,
+			s.Type,
+			millis(s.Start, origin),
+			millis(s.End, origin),
+			s.Outcome,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ganttPalette 依物品類型名稱排序後輪流使用的顏色
+var ganttPalette = []string{"#4e79a7", "#f28e2b", "#59a14f", "#e15759", "#76b7b2", "#edc948", "#b07aa1", "#9c755f"}
+
+const (
+	ganttLabelWidth = 90
+	ganttChartWidth = 900
+	ganttRowHeight  = 28
+	ganttTopMargin  = 30
+)
+
+// WriteTimelineSVG 輸出甘特圖, 每位員工一列, 依物品類型上色, 故障的處理以斜線標示
+func WriteTimelineSVG(w io.Writer, spans []Span, origin time.Time) error {
+	var employees []int
+	seen := make(map[int]bool)
+	typeSet := make(map[string]bool)
+	end := origin
+	for _, s := range spans {
+		if !seen[s.Employee] {
+			seen[s.Employee] = true
+			employees = append(employees, s.Employee)
+		}
+		typeSet[s.Type] = true
+		if s.End.After(end) {
+			end = s.End
+		}
+	}
+	sort.Ints(employees)
+	types := make([]string, 0, len(typeSet))
+	for kind := range typeSet {
+		types = append(types, kind)
+	}
+	sort.Strings(types)
+	colors := make(map[string]string, len(types))
+	for i, kind := range types {
+		colors[kind] = ganttPalette[i%len(ganttPalette)]
+	}
+	row := make(map[int]int, len(employees))
+	for i, id := range employees {
+		row[id] = i
+	}
+
+	total := end.Sub(origin)
+	if total <= 0 {
+		total = time.Millisecond
+	}
+	x := func(t time.Time) float64 {
+		return ganttLabelWidth + float64(t.Sub(origin))/float64(total)*ganttChartWidth
+	}
+	chartBottom := ganttTopMargin + len(employees)*ganttRowHeight
+	width := ganttLabelWidth + ganttChartWidth + 20
+	height := chartBottom + 60
+
+	ew := &errWriter{w: w}
+	ew.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`+"\n", width, height)
+	ew.printf(`<defs><pattern id="crashed" width="6" height="6" patternUnits="userSpaceOnUse" patternTransform="rotate(45)"><rect width="3" height="6" fill="#000" fill-opacity="0.35"/></pattern></defs>` + "\n")
+	ew.printf(`<rect width="100%%" height="100%%" fill="#fff"/>` + "\n")
+
+	// 時間刻度
+	step := tickStep(total)
+	for t := time.Duration(0); t <= total; t += step {
+		tx := x(origin.Add(t))
+		ew.printf(`<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#ddd"/>`+"\n", tx, ganttTopMargin, tx, chartBottom)
+		ew.printf(`<text x="%.1f" y="%d" text-anchor="middle" fill="#555">%v</text>`+"\n", tx, ganttTopMargin-8, t)
+	}
+
+	for _, id := range employees {
+		y := ganttTopMargin + row[id]*ganttRowHeight
+		ew.printf(`<text x="8" y="%d">員工 #%d</text>`+"\n", y+ganttRowHeight/2+4, id)
+	}
+	for _, s := range spans {
+		x1, x2 := x(s.Start), x(s.End)
+		if x2-x1 < 1 {
+			x2 = x1 + 1
+		}
+		y := ganttTopMargin + row[s.Employee]*ganttRowHeight + 3
+		title := html.EscapeString(fmt.Sprintf("員工 #%d %s %s-%sms %s",
+			s.Employee, s.Item, millis(s.Start, origin), millis(s.End, origin), s.Outcome))
+		ew.printf(`<g><title>%s</title><rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" stroke="#fff"/>`,
+			title, x1, y, x2-x1, ganttRowHeight-6, colors[s.Type])
+		if s.Outcome == OutcomeCrashed {
+			ew.printf(`<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="url(#crashed)"/>`, x1, y, x2-x1, ganttRowHeight-6)
+		}
+		ew.printf("</g>\n")
+	}
+
+	// 圖例
+	lx := ganttLabelWidth
+	for _, kind := range types {
+		ew.printf(`<rect x="%d" y="%d" width="12" height="12" fill="%s"/><text x="%d" y="%d">%s</text>`+"\n",
+			lx, chartBottom+20, colors[kind], lx+16, chartBottom+30, html.EscapeString(kind))
+		lx += 100
+	}
+	ew.printf("</svg>\n")
+	return ew.err
+}
+
+// tickStep 讓時間軸大約有 10 個刻度
+func tickStep(total time.Duration) time.Duration {
+	for _, step := range []time.Duration{
+		time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 50 * time.Millisecond,
+		100 * time.Millisecond, 500 * time.Millisecond, time.Second, 5 * time.Second,
+		10 * time.Second, 30 * time.Second, time.Minute, 5 * time.Minute,
+	} {
+		if total/step <= 10 {
+			return step
+		}
+	}
+	return total / 10
+}
+
+// errWriter 記住第一個寫入錯誤, 之後的寫入略過
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...any) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
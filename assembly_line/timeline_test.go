@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAssemblyLine_Timeline 驗證每件完成及故障的處理都記錄在時間軸上
+func TestAssemblyLine_Timeline(t *testing.T) {
+	line := NewAssemblyLine(2, WithFailures(Failure{AtAttempts: []int{1}, Recovery: time.Millisecond}))
+	stats := line.Run(newTestItems(3, 2*time.Millisecond, "A", "B"))
+
+	completed, crashed := 0, 0
+	for _, s := range stats.Timeline {
+		if !s.End.After(s.Start) && s.Outcome == OutcomeCompleted {
+			t.Errorf("span %+v ends before it starts", s)
+		}
+		switch s.Outcome {
+		case OutcomeCompleted:
+			completed++
+		case OutcomeCrashed:
+			crashed++
+		}
+	}
+	if completed != 6 || crashed != 1 {
+		t.Errorf("completed = %d, crashed = %d, want 6, 1", completed, crashed)
+	}
+}
+
+// TestWriteTimelineCSV 驗證 CSV 欄位及相對時間
+func TestWriteTimelineCSV(t *testing.T) {
+	origin := time.Now()
+	spans := []Span{
+		{Employee: 1, Item: "A #1", Type: "A", Start: origin, End: origin.Add(1500 * time.Microsecond), Outcome: OutcomeCompleted},
+	}
+	var buf bytes.Buffer
+	if err := WriteTimelineCSV(&buf, spans, origin); err != nil {
+		t.Fatalf("WriteTimelineCSV() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	want := [][]string{
+		{"employee", "item", "type", "start_ms", "end_ms", "outcome"},
+		{"1", "A #1", "A", "0.000", "1.500", "completed"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(records), len(want))
+	}
+	for i := range want {
+		if strings.Join(records[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("record %d = %v, want %v", i, records[i], want[i])
+		}
+	}
+}
+
+// TestWriteTimelineSVG 驗證甘特圖為合法 XML, 每位員工一列且依類型上色
+func TestWriteTimelineSVG(t *testing.T) {
+	origin := time.Now()
+	spans := []Span{
+		{Employee: 2, Item: "A #1", Type: "A", Start: origin, End: origin.Add(10 * time.Millisecond), Outcome: OutcomeCompleted},
+		{Employee: 1, Item: "B <1>", Type: "B", Start: origin, End: origin.Add(20 * time.Millisecond), Outcome: OutcomeCrashed},
+	}
+	var buf bytes.Buffer
+	if err := WriteTimelineSVG(&buf, spans, origin); err != nil {
+		t.Fatalf("WriteTimelineSVG() error = %v", err)
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid svg: %v", err)
+		}
+	}
+
+	out := buf.String()
+	for _, want := range []string{"員工 #1", "員工 #2", ganttPalette[0], ganttPalette[1], "url(#crashed)", "B &lt;1&gt;"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("svg does not contain %q", want)
+		}
+	}
+}
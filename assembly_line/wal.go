@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ItemCodec 將物品轉為位元組及還原, 供預寫日誌保存物品
+type ItemCodec interface {
+	Encode(item Item) ([]byte, error)
+	Decode(data []byte) (Item, error)
+}
+
+// 預寫日誌的紀錄種類
+const (
+	walSubmit = "submit"
+	walStart  = "start"
+	walFail   = "fail"
+	walDone   = "done"
+)
+
+// walCompactMin 日誌至少有這麼多筆紀錄時才自動壓縮
+const walCompactMin = 1024
+
+// walRecord 預寫日誌中的一筆紀錄, 每筆一行 JSON
+type walRecord struct {
+	Op   string `json:"op"`
+	ID   uint64 `json:"id"`
+	Data []byte `json:"data,omitempty"`
+	Err  string `json:"err,omitempty"`
+}
+
+// WAL 只附加寫入的預寫日誌, 記錄物品的提交、開始、故障及完成,
+// 重新啟動時讀出尚未完成的物品再交給員工處理.
+// 每次提交有各自的紀錄編號, 之後的紀錄都以編號對應, 同一件物品可以提交多次.
+// 提交及完成的紀錄會同步到磁碟, 開始及故障紀錄則不等待同步
+type WAL struct {
+	path  string
+	codec ItemCodec
+
+	mu sync.Mutex
+	f  *os.File
+	// replayed 上次未完成、由 OpenWAL 讀出的物品對應的紀錄編號, 再次提交時沿用一次;
+	// 不可比較的物品改以編碼內容對應, 記在 replayedData
+	replayed     map[Item]uint64
+	replayedData map[string][]uint64
+	// live 為尚未完成的紀錄的編碼內容, 供壓縮時重寫
+	live    map[uint64][]byte
+	nextID  uint64
+	records int
+	// err 第一次寫入失敗的錯誤, 之後不再寫入
+	err error
+}
+
+// OpenWAL 開啟或建立 path 的預寫日誌, 回傳上次尚未完成的物品 (依提交順序);
+// 這些物品再次提交時沿用原本的紀錄, 不會重複寫入
+func OpenWAL(path string, codec ItemCodec) (*WAL, []Item, error) {
+	w := &WAL{
+		path:         path,
+		codec:        codec,
+		replayed:     make(map[Item]uint64),
+		replayedData: make(map[string][]uint64),
+		live:         make(map[uint64][]byte),
+	}
+	if err := w.replay(); err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]uint64, 0, len(w.live))
+	for id := range w.live {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	pending := make([]Item, 0, len(ids))
+	for _, id := range ids {
+		item, err := codec.Decode(w.live[id])
+		if err != nil {
+			return nil, nil, fmt.Errorf("wal: decode item %d: %w", id, err)
+		}
+		if hashable(item) {
+			w.replayed[item] = id
+		} else {
+			key := string(w.live[id])
+			w.replayedData[key] = append(w.replayedData[key], id)
+		}
+		pending = append(pending, item)
+	}
+
+	// 重寫成只剩未完成的物品, 同時去掉寫到一半的最後一行
+	if err := w.compact(); err != nil {
+		return nil, nil, err
+	}
+	return w, pending, nil
+}
+
+// replay 讀取既有日誌, 重建尚未完成的物品
+func (w *WAL) replay() error {
+	f, err := os.Open(w.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// 沒有換行的最後一行是寫到一半就中斷的紀錄, 忽略
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var rec walRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("wal: %s line %d: %w", w.path, n, err)
+		}
+		if rec.ID >= w.nextID {
+			w.nextID = rec.ID + 1
+		}
+		switch rec.Op {
+		case walSubmit:
+			w.live[rec.ID] = rec.Data
+		case walDone:
+			delete(w.live, rec.ID)
+		case walStart, walFail:
+			// 不影響是否完成, 只留在日誌中供追查
+		default:
+			return fmt.Errorf("wal: %s line %d: unknown op %q", w.path, n, rec.Op)
+		}
+	}
+}
+
+// hashable 物品是否可作為 map 的鍵
+func hashable(item Item) bool {
+	return reflect.TypeOf(item).Comparable()
+}
+
+// Submit 記錄一次提交並同步到磁碟, 回傳其紀錄編號, 之後以編號記錄開始、故障及完成;
+// 第一次重新提交 OpenWAL 讀出的物品 (不可比較時為內容相同的物品) 時沿用原本的編號, 不會再次寫入
+func (w *WAL) Submit(item Item) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return 0, w.err
+	}
+	if hashable(item) {
+		if id, ok := w.replayed[item]; ok {
+			delete(w.replayed, item)
+			return id, nil
+		}
+	}
+
+	data, err := w.codec.Encode(item)
+	if err != nil {
+		return 0, fmt.Errorf("wal: encode %s: %w", item.String(), err)
+	}
+	if ids := w.replayedData[string(data)]; len(ids) > 0 && !hashable(item) {
+		if len(ids) == 1 {
+			delete(w.replayedData, string(data))
+		} else {
+			w.replayedData[string(data)] = ids[1:]
+		}
+		return ids[0], nil
+	}
+	id := w.nextID
+	w.nextID++
+	if err := w.append(walRecord{Op: walSubmit, ID: id, Data: data}, true); err != nil {
+		return 0, err
+	}
+	w.live[id] = data
+	return id, nil
+}
+
+// Started 記錄員工開始處理編號 id 的提交
+func (w *WAL) Started(id uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.live[id]; ok {
+		w.append(walRecord{Op: walStart, ID: id}, false)
+	}
+}
+
+// Failed 記錄一次處理失敗, 提交仍未完成, 會再交給員工處理
+func (w *WAL) Failed(id uint64, reason string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.live[id]; ok {
+		w.append(walRecord{Op: walFail, ID: id, Err: reason}, false)
+	}
+}
+
+// Completed 記錄提交處理完成並同步到磁碟, 之後重新啟動不會再處理;
+// err 為物品本身回傳的錯誤, 同樣視為完成
+func (w *WAL) Completed(id uint64, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.live[id]; !ok {
+		return
+	}
+	rec := walRecord{Op: walDone, ID: id}
+	if err != nil {
+		rec.Err = err.Error()
+	}
+	if w.append(rec, true) != nil {
+		return
+	}
+	delete(w.live, id)
+
+	if w.records >= walCompactMin && w.records > 4*len(w.live) {
+		w.compact()
+	}
+}
+
+// Pending 尚未完成的物品數
+func (w *WAL) Pending() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.live)
+}
+
+// Err 回傳第一次寫入失敗的錯誤
+func (w *WAL) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Compact 將日誌重寫成只剩尚未完成物品的提交紀錄
+func (w *WAL) Compact() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	return w.compact()
+}
+
+// Close 關閉日誌檔
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+// append 寫入一筆紀錄, 呼叫時需持有鎖; 失敗後不再寫入
+func (w *WAL) append(rec walRecord, sync bool) error {
+	if w.err != nil {
+		return w.err
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := w.f.Write(append(line, '\n')); err != nil {
+		w.err = fmt.Errorf("wal: %w", err)
+		return w.err
+	}
+	if sync {
+		if err := w.f.Sync(); err != nil {
+			w.err = fmt.Errorf("wal: %w", err)
+			return w.err
+		}
+	}
+	w.records++
+	return nil
+}
+
+// compact 寫入暫存檔後改名取代原本的日誌, 呼叫時需持有鎖
+func (w *WAL) compact() error {
+	ids := make([]uint64, 0, len(w.live))
+	for id := range w.live {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	tmp := w.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("wal: compact: %w", err)
+	}
+	bw := bufio.NewWriter(f)
+	enc := json.NewEncoder(bw)
+	for _, id := range ids {
+		if err := enc.Encode(walRecord{Op: walSubmit, ID: id, Data: w.live[id]}); err != nil {
+			f.Close()
+			return fmt.Errorf("wal: compact: %w", err)
+		}
+	}
+	if err := bw.Flush(); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, w.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("wal: compact: %w", err)
+	}
+	syncDir(filepath.Dir(w.path))
+
+	if w.f != nil {
+		w.f.Close()
+	}
+	w.f, err = os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		w.err = fmt.Errorf("wal: %w", err)
+		return w.err
+	}
+	w.records = len(ids)
+	return nil
+}
+
+// syncDir 同步目錄, 讓改名後的檔案在當機後仍然存在
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// WithWAL 以預寫日誌記錄每件物品的提交、開始、故障及完成
+func WithWAL(w *WAL) Option {
+	return func(l *AssemblyLine) {
+		l.wal = w
+	}
+}
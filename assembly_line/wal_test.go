@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCodec 以 JSON 保存 testItem 的測試編碼
+type testCodec struct{}
+
+type testItemJSON struct {
+	Kind string        `json:"kind"`
+	ID   int           `json:"id"`
+	D    time.Duration `json:"d"`
+}
+
+func (testCodec) Encode(item Item) ([]byte, error) {
+	i := item.(*testItem)
+	return json.Marshal(testItemJSON{Kind: i.kind, ID: i.id, D: i.d})
+}
+
+func (testCodec) Decode(data []byte) (Item, error) {
+	var v testItemJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return &testItem{kind: v.Kind, id: v.ID, d: v.D}, nil
+}
+
+// openTestWAL 開啟測試用的預寫日誌
+func openTestWAL(t *testing.T, path string) (*WAL, []Item) {
+	t.Helper()
+	w, pending, err := OpenWAL(path, testCodec{})
+	if err != nil {
+		t.Fatalf("OpenWAL() error = %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w, pending
+}
+
+// walLines 讀出日誌中每一行
+func walLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+// TestWAL_Replay 驗證重新開啟時只讀出尚未完成的物品, 並依提交順序排列
+func TestWAL_Replay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "line.wal")
+	w, pending := openTestWAL(t, path)
+	if len(pending) != 0 {
+		t.Fatalf("new WAL pending = %v, want none", pending)
+	}
+	items := newTestItems(2, time.Millisecond, "A", "B")
+	ids := make([]uint64, len(items))
+	for i, item := range items {
+		id, err := w.Submit(item)
+		if err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+		ids[i] = id
+	}
+	w.Started(ids[0])
+	w.Completed(ids[0], nil)
+	w.Started(ids[2])
+	w.Failed(ids[2], "crash")
+	w.Close()
+
+	w, pending = openTestWAL(t, path)
+	want := []string{"A #2", "B #1", "B #2"}
+	if len(pending) != len(want) {
+		t.Fatalf("pending = %v, want %v", pending, want)
+	}
+	for i := range want {
+		if pending[i].String() != want[i] {
+			t.Errorf("pending[%d] = %s, want %s", i, pending[i], want[i])
+		}
+	}
+	if w.Pending() != 3 {
+		t.Errorf("Pending() = %d, want 3", w.Pending())
+	}
+	// 開啟時已壓縮成只剩未完成物品的提交紀錄
+	if lines := walLines(t, path); len(lines) != 3 {
+		t.Errorf("log has %d lines after open, want 3:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+
+	// 再次提交讀出的物品不會重複寫入, 但同一件物品第二次提交是新的紀錄
+	for _, item := range pending {
+		if _, err := w.Submit(item); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+	if lines := walLines(t, path); len(lines) != 3 {
+		t.Errorf("log has %d lines after resubmit, want 3", len(lines))
+	}
+	if _, err := w.Submit(pending[0]); err != nil || w.Pending() != 4 {
+		t.Errorf("second Submit() error = %v, Pending() = %d, want 4", err, w.Pending())
+	}
+}
+
+// TestWAL_SubmitTwice 驗證同一件物品提交兩次各自有紀錄, 完成一次不會讓另一次失去保存
+func TestWAL_SubmitTwice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "line.wal")
+	w, _ := openTestWAL(t, path)
+	item := &testItem{kind: "A", id: 1}
+	first, _ := w.Submit(item)
+	second, _ := w.Submit(item)
+	if first == second || w.Pending() != 2 {
+		t.Fatalf("ids = %d, %d, Pending() = %d, want distinct ids and 2", first, second, w.Pending())
+	}
+	w.Completed(first, nil)
+	w.Close()
+
+	if _, pending := openTestWAL(t, path); len(pending) != 1 || pending[0].String() != "A #1" {
+		t.Errorf("pending = %v, want [A #1]", pending)
+	}
+}
+
+// TestWAL_TornTail 驗證寫到一半的最後一行會被忽略, 其他損毀則回傳錯誤
+func TestWAL_TornTail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "line.wal")
+	w, _ := openTestWAL(t, path)
+	item := &testItem{kind: "A", id: 1}
+	w.Submit(item)
+	w.Close()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"done","id":`)
+	f.Close()
+	if _, pending := openTestWAL(t, path); len(pending) != 1 {
+		t.Errorf("pending = %v, want the submitted item", pending)
+	}
+
+	bad := filepath.Join(dir, "bad.wal")
+	os.WriteFile(bad, []byte("not json\n"), 0o644)
+	if _, _, err := OpenWAL(bad, testCodec{}); err == nil {
+		t.Error("OpenWAL(corrupt) error = nil, want error")
+	}
+}
+
+// TestWAL_Compact 驗證壓縮後只剩未完成物品
+func TestWAL_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "line.wal")
+	w, _ := openTestWAL(t, path)
+	items := newTestItems(10, 0, "A")
+	ids := make([]uint64, len(items))
+	for i, item := range items {
+		ids[i], _ = w.Submit(item)
+		w.Started(ids[i])
+	}
+	for _, id := range ids[:8] {
+		w.Completed(id, nil)
+	}
+	if n := len(walLines(t, path)); n != 28 {
+		t.Errorf("log has %d lines before compaction, want 28", n)
+	}
+	if err := w.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if n := len(walLines(t, path)); n != 2 {
+		t.Errorf("log has %d lines after compaction, want 2", n)
+	}
+
+	// 壓縮後仍可繼續寫入
+	w.Completed(ids[8], nil)
+	w.Close()
+	if _, pending := openTestWAL(t, path); len(pending) != 1 || pending[0].String() != "A #10" {
+		t.Errorf("pending = %v, want [A #10]", pending)
+	}
+}
+
+// TestAssemblyLine_WALRecovery 驗證當機後重新啟動, 未完成的物品只會再處理一次
+func TestAssemblyLine_WALRecovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "line.wal")
+
+	// 模擬上次執行: 提交 5 件, 只完成 2 件就當機
+	w, _ := openTestWAL(t, path)
+	items := newTestItems(5, time.Millisecond, "A")
+	ids := make([]uint64, len(items))
+	for i, item := range items {
+		ids[i], _ = w.Submit(item)
+	}
+	for _, id := range ids[:2] {
+		w.Started(id)
+		w.Completed(id, nil)
+	}
+	w.Started(ids[2])
+	w.Close()
+
+	w, pending := openTestWAL(t, path)
+	if len(pending) != 3 {
+		t.Fatalf("pending = %v, want 3 items", pending)
+	}
+	// 故障的員工讓物品重新排入, 日誌中會有故障紀錄
+	line := NewAssemblyLine(2, WithWAL(w), WithSeed(1),
+		WithFailures(Failure{AtAttempts: []int{1}, Recovery: time.Millisecond}))
+	stats := line.Run(pending)
+	if stats.TotalProcessed() != 3 {
+		t.Errorf("TotalProcessed = %d, want 3", stats.TotalProcessed())
+	}
+
+	ops := make(map[string]int)
+	for _, l := range walLines(t, path) {
+		var rec walRecord
+		if err := json.Unmarshal([]byte(l), &rec); err != nil {
+			t.Fatal(err)
+		}
+		ops[rec.Op]++
+	}
+	if ops[walSubmit] != 3 || ops[walDone] != 3 || ops[walFail] != 1 || ops[walStart] != 4 {
+		t.Errorf("log ops = %v, want 3 submit, 4 start, 1 fail, 3 done", ops)
+	}
+	w.Close()
+
+	if _, pending := openTestWAL(t, path); len(pending) != 0 {
+		t.Errorf("pending after recovery = %v, want none", pending)
+	}
+}
+
+// partsCodec 以 JSON 保存 partsItem 的測試編碼
+type partsCodec struct{}
+
+func (partsCodec) Encode(item Item) ([]byte, error) { return json.Marshal(item.(partsItem).parts) }
+
+func (partsCodec) Decode(data []byte) (Item, error) {
+	var parts []string
+	err := json.Unmarshal(data, &parts)
+	return partsItem{parts: parts}, err
+}
+
+// TestAssemblyLine_WALUnhashable 驗證不可比較的物品也能寫入日誌, 且重新開啟後可再提交
+func TestAssemblyLine_WALUnhashable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "line.wal")
+	w, _, err := OpenWAL(path, partsCodec{})
+	if err != nil {
+		t.Fatalf("OpenWAL() error = %v", err)
+	}
+	item := partsItem{parts: []string{"a", "b"}}
+	w.Submit(item)
+	w.Close()
+
+	w, pending, err := OpenWAL(path, partsCodec{})
+	if err != nil {
+		t.Fatalf("OpenWAL() error = %v", err)
+	}
+	defer w.Close()
+	stats := NewAssemblyLine(1, WithWAL(w)).Run(append(pending, item))
+	if stats.TotalProcessed() != 2 || w.Pending() != 0 {
+		t.Errorf("TotalProcessed() = %d, Pending() = %d, want 2 and 0", stats.TotalProcessed(), w.Pending())
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// deque 員工自己的物品佇列, 主人從前端取, 其他員工從尾端偷
+type deque struct {
+	mu    sync.Mutex
+	items []Item
+	// size 供竊取者不加鎖地挑選目標
+	size int64
+}
+
+func (q *deque) pushBack(items ...Item) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.items = append(q.items, items...)
+	atomic.StoreInt64(&q.size, int64(len(q.items)))
+}
+
+func (q *deque) popFront() (Item, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	item := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+	atomic.StoreInt64(&q.size, int64(len(q.items)))
+	return item, true
+}
+
+// stealHalf 從尾端取走一半 (至少一件) 物品
+func (q *deque) stealHalf() []Item {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := len(q.items)
+	if n == 0 {
+		return nil
+	}
+	k := n - (n+1)/2
+	stolen := make([]Item, n-k)
+	copy(stolen, q.items[k:])
+	for i := k; i < n; i++ {
+		q.items[i] = nil
+	}
+	q.items = q.items[:k]
+	atomic.StoreInt64(&q.size, int64(k))
+	return stolen
+}
+
+func (q *deque) len() int64 {
+	return atomic.LoadInt64(&q.size)
+}
+
+// workStealingDispatcher 每位員工有自己的佇列, 物品輪流放入,
+// 員工自己的佇列空了就從剩最多物品的同事那裡偷走一半
+type workStealingDispatcher struct {
+	deques []*deque
+	owner  map[*Employee]int
+	steals int64
+
+	mu      sync.Mutex
+	next    int
+	closed  bool
+	changed chan struct{}
+	// waiting 有員工在等待 changed 時才需要通知
+	waiting bool
+}
+
+// NewWorkStealingDispatcher 創建工作竊取派發器
+func NewWorkStealingDispatcher(employees []*Employee) Dispatcher {
+	n := len(employees)
+	if n == 0 {
+		n = 1
+	}
+	d := &workStealingDispatcher{
+		deques:  make([]*deque, n),
+		owner:   make(map[*Employee]int, len(employees)),
+		changed: make(chan struct{}),
+	}
+	for i := range d.deques {
+		d.deques[i] = &deque{}
+	}
+	for i, e := range employees {
+		d.owner[e] = i
+	}
+	return d
+}
+
+func (d *workStealingDispatcher) Push(item Item) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deques[d.next%len(d.deques)].pushBack(item)
+	d.next++
+	d.broadcast()
+}
+
+func (d *workStealingDispatcher) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	d.broadcast()
+}
+
+// broadcast 喚醒所有等待中的員工, 呼叫時需持有鎖
+func (d *workStealingDispatcher) broadcast() {
+	if !d.waiting {
+		return
+	}
+	close(d.changed)
+	d.changed = make(chan struct{})
+	d.waiting = false
+}
+
+func (d *workStealingDispatcher) Next(ctx context.Context, e *Employee) (Item, error) {
+	for {
+		if item, ok := d.take(e); ok {
+			return item, nil
+		}
+
+		// 取得通知 channel 後再檢查一次, 避免錯過期間放入的物品
+		d.mu.Lock()
+		changed, closed := d.changed, d.closed
+		d.waiting = true
+		d.mu.Unlock()
+		if item, ok := d.take(e); ok {
+			return item, nil
+		}
+		if closed {
+			return nil, ErrDrained
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// take 先取自己佇列的物品, 沒有時再偷
+func (d *workStealingDispatcher) take(e *Employee) (Item, bool) {
+	own, hasOwn := d.owner[e]
+	if hasOwn {
+		if item, ok := d.deques[own].popFront(); ok {
+			return item, true
+		}
+	}
+
+	victim, stolen := d.steal()
+	if len(stolen) == 0 {
+		return nil, false
+	}
+	atomic.AddInt64(&d.steals, 1)
+	// 偷來的第一件立即處理, 其餘放進自己的佇列; 沒有佇列的員工則放回原處
+	if !hasOwn {
+		own = victim
+	}
+	if len(stolen) > 1 {
+		d.deques[own].pushBack(stolen[1:]...)
+		// 偷走到放回之間所有佇列看似為空, 期間開始等待的員工需要喚醒
+		d.mu.Lock()
+		d.broadcast()
+		d.mu.Unlock()
+	}
+	return stolen[0], true
+}
+
+// victim 剩最多物品的佇列, 全部為空時回傳 -1
+func (d *workStealingDispatcher) victim() int {
+	victim, most := -1, int64(0)
+	for i, q := range d.deques {
+		if n := q.len(); n > most {
+			victim, most = i, n
+		}
+	}
+	return victim
+}
+
+// steal 從剩最多物品的佇列尾端偷走一半
+func (d *workStealingDispatcher) steal() (int, []Item) {
+	for {
+		victim := d.victim()
+		if victim < 0 {
+			return -1, nil
+		}
+		// 挑選與取出之間可能被其他人拿走, 重新挑選
+		if stolen := d.deques[victim].stealHalf(); len(stolen) > 0 {
+			return victim, stolen
+		}
+	}
+}
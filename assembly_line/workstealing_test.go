@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestWorkStealingDispatcher_RoundRobin 驗證物品輪流放入各員工佇列
+func TestWorkStealingDispatcher_RoundRobin(t *testing.T) {
+	employees := []*Employee{{ID: 1}, {ID: 2}}
+	d := NewWorkStealingDispatcher(employees).(*workStealingDispatcher)
+	items := newTestItems(2, 0, "A", "B")
+	for _, item := range items {
+		d.Push(item)
+	}
+	d.Close()
+
+	// A#1, A#2, B#1, B#2 -> 員工 1: A#1, B#1; 員工 2: A#2, B#2
+	for i, want := range []Item{items[0], items[2]} {
+		got, err := d.Next(context.Background(), employees[0])
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("employee 1 item %d = %s, want %s", i, got, want)
+		}
+	}
+	if d.steals != 0 {
+		t.Errorf("steals = %d, want 0", d.steals)
+	}
+}
+
+// TestWorkStealingDispatcher_Steal 驗證自己的佇列空了會從同事尾端偷
+func TestWorkStealingDispatcher_Steal(t *testing.T) {
+	employees := []*Employee{{ID: 1}, {ID: 2}}
+	d := NewWorkStealingDispatcher(employees).(*workStealingDispatcher)
+	items := newTestItems(4, 0, "A")
+	for _, item := range items {
+		d.Push(item)
+	}
+	d.Close()
+
+	// 員工 1 取完全部: 自己的 #1, #3, 再偷員工 2 尾端的 #4, #2
+	got := drain(t, d, employees[0])
+	want := []Item{items[0], items[2], items[3], items[1]}
+	if len(got) != len(want) {
+		t.Fatalf("got %d items, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("item %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+	if d.steals != 2 {
+		t.Errorf("steals = %d, want 2", d.steals)
+	}
+}
+
+// TestWorkStealingDispatcher_WakeOnPush 驗證等待中的員工會被新物品喚醒
+func TestWorkStealingDispatcher_WakeOnPush(t *testing.T) {
+	employees := []*Employee{{ID: 1}, {ID: 2}}
+	d := NewWorkStealingDispatcher(employees)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		d.Push(&testItem{kind: "A"})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := d.Next(ctx, employees[1]); err != nil {
+		t.Errorf("Next() error = %v", err)
+	}
+}
+
+// TestWorkStealingDispatcher_WakeAfterSteal 驗證偷來的物品放進自己的佇列後會喚醒等待中的員工
+func TestWorkStealingDispatcher_WakeAfterSteal(t *testing.T) {
+	employees := []*Employee{{ID: 1}, {ID: 2}, {ID: 3}}
+	d := NewWorkStealingDispatcher(employees).(*workStealingDispatcher)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	got := make(chan error, 1)
+	go func() {
+		_, err := d.Next(ctx, employees[1])
+		got <- err
+	}()
+	for {
+		d.mu.Lock()
+		waiting := d.waiting
+		d.mu.Unlock()
+		if waiting {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// 模擬偷取途中: 物品已在員工 3 的佇列, 但等待中的員工沒有被通知
+	d.deques[2].pushBack(newTestItems(4, 0, "A")...)
+	if _, ok := d.take(employees[0]); !ok {
+		t.Fatal("take() ok = false, want a stolen item")
+	}
+	if err := <-got; err != nil {
+		t.Errorf("waiting employee Next() error = %v, want woken by the steal", err)
+	}
+}
+
+// TestAssemblyLine_WorkStealing 驗證工作竊取派發下所有物品都被處理
+func TestAssemblyLine_WorkStealing(t *testing.T) {
+	line := NewAssemblyLine(5, WithDispatcher(NewWorkStealingDispatcher))
+	stats := line.Run(newTestItems(10, time.Millisecond, "A", "B", "C"))
+	if got := stats.TotalProcessed(); got != 30 {
+		t.Errorf("TotalProcessed = %d, want 30", got)
+	}
+}
+
+// benchmarkDispatcher 讓多位員工並發清空派發器, 比較派發器本身的競爭開銷
+func benchmarkDispatcher(b *testing.B, newDispatcher DispatcherFactory) {
+	const numItems = 10000
+	employees := make([]*Employee, 8)
+	for i := range employees {
+		employees[i] = &Employee{ID: i + 1}
+	}
+	items := newTestItems(numItems, 0, "A")
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		d := newDispatcher(employees)
+		for _, item := range items {
+			d.Push(item)
+		}
+		d.Close()
+
+		var wg sync.WaitGroup
+		for _, e := range employees {
+			wg.Add(1)
+			go func(e *Employee) {
+				defer wg.Done()
+				for {
+					if _, err := d.Next(context.Background(), e); err != nil {
+						return
+					}
+				}
+			}(e)
+		}
+		wg.Wait()
+	}
+}
+
+func BenchmarkDispatcher_SharedChannel(b *testing.B) {
+	benchmarkDispatcher(b, func(employees []*Employee) Dispatcher {
+		return NewFIFODispatcher(10000)
+	})
+}
+
+func BenchmarkDispatcher_WorkStealing(b *testing.B) {
+	benchmarkDispatcher(b, NewWorkStealingDispatcher)
+}